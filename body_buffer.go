@@ -0,0 +1,139 @@
+package eisodos
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/darshan-rambhia/eisodos/config"
+)
+
+// defaultBufferMemoryBytes is how much of a body is kept in memory before
+// spilling to a temporary file
+const defaultBufferMemoryBytes = 64 << 10
+
+// bodyBufferPolicy decides how request bodies are buffered
+type bodyBufferPolicy struct {
+	maxBytes    int64
+	memoryBytes int64
+	tempDir     string
+}
+
+func newBodyBufferPolicy(cfg config.RequestBufferConfig) *bodyBufferPolicy {
+	memoryBytes := cfg.MemoryBytes
+	if memoryBytes <= 0 {
+		memoryBytes = defaultBufferMemoryBytes
+	}
+
+	return &bodyBufferPolicy{
+		maxBytes:    cfg.MaxBytes,
+		memoryBytes: min(memoryBytes, cfg.MaxBytes),
+		tempDir:     cfg.TempDir,
+	}
+}
+
+// bodyBuffer holds a request body so that it can be sent more than once.
+// It is released once every holder has closed it.
+type bodyBuffer struct {
+	mem  []byte
+	file *os.File
+	size int64
+	refs atomic.Int32
+}
+
+// buffer reads the body of r and makes r replayable through GetBody. Bodies
+// larger than maxBytes are streamed instead: what has been read so far is put
+// back in front of the rest and r stays non-replayable, so it is never
+// retried. The returned buffer, which may be nil, must be closed once r is
+// done.
+func (p *bodyBufferPolicy) buffer(r *http.Request) (*bodyBuffer, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength > p.maxBytes {
+		return nil, nil
+	}
+
+	buf := &bodyBuffer{}
+	buf.refs.Store(1)
+	mem, err := io.ReadAll(io.LimitReader(r.Body, p.memoryBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	buf.mem = mem
+	buf.size = int64(len(mem))
+
+	if buf.size > p.memoryBytes && p.memoryBytes < p.maxBytes {
+		if err := buf.spill(r.Body, p.tempDir, p.maxBytes); err != nil {
+			buf.Close()
+			return nil, err
+		}
+	}
+
+	if buf.size > p.maxBytes {
+		r.Body = &prefixedBody{Reader: io.MultiReader(buf.reader(), r.Body), body: r.Body}
+		return buf, nil
+	}
+
+	r.Body = io.NopCloser(buf.reader())
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(buf.reader()), nil
+	}
+	r.ContentLength = buf.size
+	r.TransferEncoding = nil
+	return buf, nil
+}
+
+// spill moves the buffered bytes to a temporary file and continues reading
+// body into it, stopping one byte past maxBytes
+func (b *bodyBuffer) spill(body io.Reader, dir string, maxBytes int64) error {
+	file, err := os.CreateTemp(dir, "eisodos-body-*")
+	if err != nil {
+		return err
+	}
+	b.file = file
+
+	if _, err := file.Write(b.mem); err != nil {
+		return err
+	}
+	b.mem = nil
+
+	n, err := io.Copy(file, io.LimitReader(body, maxBytes+1-b.size))
+	b.size += n
+	return err
+}
+
+// reader returns a fresh reader over the buffered bytes
+func (b *bodyBuffer) reader() io.Reader {
+	if b.file != nil {
+		return io.NewSectionReader(b.file, 0, b.size)
+	}
+	return bytes.NewReader(b.mem)
+}
+
+// retain adds a holder that must close the buffer too. It is safe on a nil
+// buffer.
+func (b *bodyBuffer) retain() {
+	if b != nil {
+		b.refs.Add(1)
+	}
+}
+
+// Close releases the temporary file, if any, once the last holder closes
+// the buffer. It is safe on a nil buffer.
+func (b *bodyBuffer) Close() error {
+	if b == nil || b.refs.Add(-1) > 0 || b.file == nil {
+		return nil
+	}
+	return errors.Join(b.file.Close(), os.Remove(b.file.Name()))
+}
+
+// prefixedBody streams a body of which a prefix has already been read
+type prefixedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *prefixedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,112 @@
+package eisodos
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyBuffer(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		wantReplayable bool
+		wantSpill      bool
+	}{
+		{name: "small body in memory", body: strings.Repeat("a", 8), wantReplayable: true},
+		{name: "chunked body in memory", body: strings.Repeat("a", 8), chunked: true, wantReplayable: true},
+		{name: "large body spills to disk", body: strings.Repeat("b", 48), wantReplayable: true, wantSpill: true},
+		{name: "body at the cap", body: strings.Repeat("c", 64), wantReplayable: true, wantSpill: true},
+		{name: "body over the cap is streamed", body: strings.Repeat("d", 100)},
+		{name: "chunked body over the cap is streamed", body: strings.Repeat("e", 100), chunked: true, wantSpill: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := newBodyBufferPolicy(config.RequestBufferConfig{MaxBytes: 64, MemoryBytes: 16, TempDir: dir})
+
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				// Hide the length the way a chunked upload would
+				r.Body = io.NopCloser(strings.NewReader(tt.body))
+				r.ContentLength = -1
+			}
+
+			buf, err := p.buffer(r)
+			require.NoError(t, err)
+
+			spilled, _ := os.ReadDir(dir)
+			assert.Equal(t, tt.wantSpill, len(spilled) > 0, "temporary file")
+			assert.Equal(t, tt.wantReplayable, replayable(r))
+
+			// The backend sees the whole body either way
+			got, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(got))
+
+			if tt.wantReplayable {
+				assert.Equal(t, int64(len(tt.body)), r.ContentLength)
+				for i := 0; i < 2; i++ {
+					body, err := r.GetBody()
+					require.NoError(t, err)
+					got, _ := io.ReadAll(body)
+					assert.Equal(t, tt.body, string(got))
+				}
+			}
+
+			require.NoError(t, buf.Close())
+			spilled, _ = os.ReadDir(dir)
+			assert.Empty(t, spilled, "temporary file left behind")
+		})
+	}
+}
+
+func TestBodyBufferSkipsKnownOversizedBodies(t *testing.T) {
+	p := newBodyBufferPolicy(config.RequestBufferConfig{MaxBytes: 4})
+	r := httptest.NewRequest("POST", "/", strings.NewReader("too large"))
+	body := r.Body
+
+	buf, err := p.buffer(r)
+	require.NoError(t, err)
+	assert.Nil(t, buf)
+	assert.Equal(t, body, r.Body, "body was read although its length exceeds the cap")
+}
+
+func TestRetriesReplayBufferedBodies(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+	handler := func(name string, status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			received[name] = append(received[name], string(body))
+			mu.Unlock()
+			w.WriteHeader(status)
+		}
+	}
+	failing := newTestUpstream(t, handler("failing", http.StatusServiceUnavailable))
+	healthy := newTestUpstream(t, handler("healthy", http.StatusOK))
+
+	lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2}, failing.URL, healthy.URL)
+	lb.bodyBuffer = newBodyBufferPolicy(config.RequestBufferConfig{MaxBytes: 1 << 20, MemoryBytes: 4, TempDir: t.TempDir()})
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("PUT", "/", strings.NewReader("payload")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	// One of the requests was retried with its body intact
+	assert.Equal(t, []string{"payload"}, received["failing"])
+	assert.Equal(t, []string{"payload", "payload"}, received["healthy"])
+}
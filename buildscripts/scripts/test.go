@@ -33,9 +33,12 @@ func main() {
 		"-race",
 		"-timeout", testTimeout,
 		"-coverprofile=" + filepath.Join(testOutputDir, coverageFile),
+		"github.com/darshan-rambhia/eisodos",
 		"github.com/darshan-rambhia/eisodos/cmd/eisodos",
 		"github.com/darshan-rambhia/eisodos/internal/backend",
 		"github.com/darshan-rambhia/eisodos/internal/serverpool",
+		"github.com/darshan-rambhia/eisodos/internal/router",
+		"github.com/darshan-rambhia/eisodos/internal/headers",
 		"github.com/darshan-rambhia/eisodos/config",
 	}
 
@@ -3,7 +3,10 @@ package main
 import (
 	"flag"
 	"log"
+	"strings"
 	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
 )
 
 func main() {
@@ -11,7 +14,7 @@ func main() {
 	configFile := flag.String("config", "", "Path to YAML configuration file")
 	port := flag.Int("port", 8080, "Port to listen on")
 	healthCheckInterval := flag.Duration("health-check-interval", 10*time.Second, "Health check interval")
-	strategy := flag.String("strategy", "round-robin", "Load balancing strategy (round-robin or least-connected)")
+	strategy := flag.String("strategy", "round-robin", "Load balancing strategy ("+strings.Join(serverpool.StrategyNames(), ", ")+")")
 	flag.Parse()
 
 	var lb LoadBalancer
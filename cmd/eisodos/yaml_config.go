@@ -8,6 +8,7 @@ import (
 
 	"github.com/darshan-rambhia/eisodos"
 	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/backend"
 )
 
 // LoadFromYAML creates a load balancer from a YAML configuration file
@@ -20,19 +21,66 @@ func LoadFromYAML(path string) (LoadBalancer, error) {
 	builder := eisodos.NewLoadBalancerBuilder().
 		WithConfig(cfg)
 
-	for _, backend := range cfg.Backends {
-		url, err := url.Parse(backend.URL)
+	slowStart, err := cfg.SlowStart.Policy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure slow start: %w", err)
+	}
+
+	for _, backendCfg := range cfg.Backends {
+		url, proxy, opts, err := backendFromConfig(cfg, backendCfg, slowStart)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse backend URL %s: %w", backend.URL, err)
+			return nil, err
 		}
+		builder.WithBackend(url, proxy, opts...)
+	}
 
-		proxy := httputil.NewSingleHostReverseProxy(url)
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			http.Error(w, "Proxy error", http.StatusBadGateway)
+	for _, pool := range cfg.Pools {
+		for _, backendCfg := range pool.Backends {
+			url, proxy, opts, err := backendFromConfig(cfg, backendCfg, slowStart)
+			if err != nil {
+				return nil, fmt.Errorf("pool %s: %w", pool.Name, err)
+			}
+			builder.WithPoolBackend(pool.Name, url, proxy, opts...)
 		}
-
-		builder.WithBackend(url, proxy)
 	}
 
 	return builder.Build()
 }
+
+// backendFromConfig returns the URL, proxy and options of a backend
+func backendFromConfig(cfg *config.Config, backendCfg config.BackendConfig, slowStart backend.SlowStartPolicy) (*url.URL, *httputil.ReverseProxy, []backend.Option, error) {
+	url, err := url.Parse(backendCfg.URL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse backend URL %s: %w", backendCfg.URL, err)
+	}
+
+	checker, err := cfg.HealthCheckerFor(backendCfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to configure health check for %s: %w", backendCfg.URL, err)
+	}
+
+	policy, err := cfg.HealthPolicyFor(backendCfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to configure health check for %s: %w", backendCfg.URL, err)
+	}
+
+	breaker, err := cfg.CircuitBreakerFor(backendCfg)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to configure circuit breaker for %s: %w", backendCfg.URL, err)
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(url)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, "Proxy error", http.StatusBadGateway)
+	}
+
+	opts := []backend.Option{
+		backend.WithWeight(backendCfg.Weight),
+		backend.WithMaxConns(backendCfg.MaxConns),
+		backend.WithHealthChecker(checker),
+		backend.WithHealthPolicy(policy),
+		backend.WithCircuitBreaker(breaker),
+		backend.WithSlowStart(slowStart),
+	}
+	return url, proxy, opts, nil
+}
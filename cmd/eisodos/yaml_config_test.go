@@ -1,10 +1,13 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/darshan-rambhia/eisodos/internal/backend"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -109,6 +112,31 @@ backends:
 	}
 }
 
+func TestLoadFromYAMLWithWeightedStrategy(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(`
+port: 8080
+healthCheckInterval: 10s
+strategy: weighted-round-robin
+backends:
+  - url: "http://localhost:8081"
+    weight: 1
+  - url: "http://localhost:8082"
+    weight: 2
+  - url: "http://localhost:8083"
+`), 0644)
+	assert.NoError(t, err)
+
+	lb, err := LoadFromYAML(configPath)
+	assert.NoError(t, err)
+
+	backends := lb.GetBackends()
+	assert.Len(t, backends, 3)
+	assert.Equal(t, 1, backends[0].GetWeight())
+	assert.Equal(t, 2, backends[1].GetWeight())
+	assert.Equal(t, 1, backends[2].GetWeight(), "unset weight should default to 1")
+}
+
 func TestLoadFromYAMLWithInvalidFile(t *testing.T) {
 	// Test with non-existent file
 	_, err := LoadFromYAML("nonexistent.yaml")
@@ -125,3 +153,138 @@ func TestLoadFromYAMLWithInvalidFile(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to parse config file")
 }
+
+func TestLoadFromYAMLWithCircuitBreaker(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(`
+port: 8080
+healthCheckInterval: 10s
+circuitBreaker:
+  failureThreshold: 5
+backends:
+  - url: "`+upstream.URL+`"
+    circuitBreaker:
+      failureThreshold: 2
+      openTimeout: 1m
+`), 0644)
+	assert.NoError(t, err)
+
+	lb, err := LoadFromYAML(configPath)
+	assert.NoError(t, err)
+
+	handler := lb.(http.Handler)
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	backends := lb.GetBackends()
+	assert.Equal(t, backend.CircuitOpen, backends[0].GetCircuitState())
+
+	// With its only backend's circuit open the load balancer has no peer
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestLoadFromYAMLWithRoutes(t *testing.T) {
+	upstream := func(name string) *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		t.Cleanup(s.Close)
+		return s
+	}
+	web, api, admin, beta := upstream("web"), upstream("api"), upstream("admin"), upstream("beta")
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(configPath, []byte(`
+port: 8080
+healthCheckInterval: 10s
+backends:
+  - url: "`+web.URL+`"
+pools:
+  - name: api
+    strategy: least-connected
+    backends:
+      - url: "`+api.URL+`"
+  - name: admin
+    backends:
+      - url: "`+admin.URL+`"
+  - name: beta
+    backends:
+      - url: "`+beta.URL+`"
+routes:
+  - headers:
+      - name: X-Tenant
+        value: acme
+    pool: beta
+  - query:
+      - name: beta
+        value: "1"
+    pool: beta
+  - host: "*.example.com"
+    path: /api
+    pool: api
+  - path: /admin
+    methods: [GET]
+    pool: admin
+    headerRules:
+      response:
+        set:
+          - name: X-Route
+            value: "admin {method}"
+  - name: checkout
+    path: /checkout
+    split:
+      - pool: default
+        weight: 0
+      - pool: beta
+        weight: 100
+    pinBy:
+      cookie: uid
+`), 0644)
+	assert.NoError(t, err)
+
+	lb, err := LoadFromYAML(configPath)
+	assert.NoError(t, err)
+
+	handler := lb.(http.Handler)
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{"GET", "http://shop.example.com/api/orders", "api"},
+		{"GET", "http://example.org/api/orders", "web"},
+		{"GET", "http://example.org/admin", "admin"},
+		{"POST", "http://example.org/admin", "web"},
+		{"GET", "http://example.org/", "web"},
+		{"GET", "http://example.org/checkout", "beta"},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(tt.method, tt.target, nil))
+		assert.Equal(t, tt.want, recorder.Body.String(), "%s %s", tt.method, tt.target)
+	}
+
+	// Tenants and beta testers are steered to their own pool
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://shop.example.com/api/orders", nil)
+	req.Header.Set("X-Tenant", "acme")
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, "beta", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.org/?beta=1", nil))
+	assert.Equal(t, "beta", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://example.org/admin", nil))
+	assert.Equal(t, "admin GET", recorder.Header().Get("X-Route"))
+}
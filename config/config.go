@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"regexp"
 	"time"
 
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/headers"
+	"github.com/darshan-rambhia/eisodos/internal/router"
 	"github.com/darshan-rambhia/eisodos/internal/serverpool"
 	"gopkg.in/yaml.v3"
 )
@@ -14,14 +19,276 @@ type Config struct {
 	Port                int                   `yaml:"port"`
 	HealthCheckInterval time.Duration         `yaml:"healthCheckInterval"`
 	Strategy            serverpool.LBStrategy `yaml:"strategy"`
+	Queue               QueueConfig           `yaml:"queue,omitempty"`
+	Hashing             HashingConfig         `yaml:"hashing,omitempty"`
+	TrustedProxies      []string              `yaml:"trustedProxies,omitempty"`
+	ClientIPHeader      string                `yaml:"clientIPHeader,omitempty"`
+	Sticky              StickyConfig          `yaml:"stickySessions,omitempty"`
+	HealthCheck         HealthCheckConfig     `yaml:"healthCheck,omitempty"`
+	OutlierDetection    OutlierConfig         `yaml:"outlierDetection,omitempty"`
+	CircuitBreaker      CircuitBreakerConfig  `yaml:"circuitBreaker,omitempty"`
+	Retries             RetryConfig           `yaml:"retries,omitempty"`
+	RequestBuffering    RequestBufferConfig   `yaml:"requestBuffering,omitempty"`
+	Hedging             HedgeConfig           `yaml:"hedging,omitempty"`
+	SlowStart           SlowStartConfig       `yaml:"slowStart,omitempty"`
 	Backends            []BackendConfig       `yaml:"backends"`
+	Pools               []PoolConfig          `yaml:"pools,omitempty"`
+	Routes              []RouteConfig         `yaml:"routes,omitempty"`
+	DefaultPool         string                `yaml:"defaultPool,omitempty"`
+	Mirroring           MirroringConfig       `yaml:"mirroring,omitempty"`
+	HeaderRules         HeaderRulesConfig     `yaml:"headerRules,omitempty"`
 }
 
-// BackendConfig represents a backend server configuration
+// DefaultPoolName names the pool of the top level backends, which serves
+// requests matching no route unless DefaultPool names another pool
+const DefaultPoolName = "default"
+
+// QueueConfig controls how requests wait when every backend of their pool is
+// at maxConns. Each pool queues up to Size requests.
+type QueueConfig struct {
+	Size    int           `yaml:"size"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// HashingConfig configures hash based strategies. Key is one of ip, header,
+// cookie or path; Name is the header or cookie name. Epsilon bounds the load
+// of consistent-hash-bounded to (1+epsilon) times the average; it defaults to
+// 0.25 when unset and an explicit 0 balances the load perfectly.
+type HashingConfig struct {
+	Key          string   `yaml:"key,omitempty"`
+	Name         string   `yaml:"name,omitempty"`
+	VirtualNodes int      `yaml:"virtualNodes,omitempty"`
+	Epsilon      *float64 `yaml:"epsilon,omitempty"`
+}
+
+// StickyConfig configures cookie based session persistence on top of the
+// strategy. Key signs the cookie and must be kept secret.
+type StickyConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	CookieName string        `yaml:"cookieName,omitempty"`
+	TTL        time.Duration `yaml:"ttl,omitempty"`
+	SameSite   string        `yaml:"sameSite,omitempty"`
+	Secure     bool          `yaml:"secure,omitempty"`
+	Key        string        `yaml:"key"`
+}
+
+// minStickyKeyLength is the shortest accepted HMAC key, in bytes
+const minStickyKeyLength = 16
+
+// HealthCheckConfig describes how backends are probed. Type is tcp (the
+// default), http or grpc; Path through BodyRegex apply to http checks and
+// Service and TLS to grpc checks, which use h2c unless TLS is set or the
+// backend URL is https.
+// ExpectedStatus entries take the form "200", "200-299" or "2xx".
+// Interval defaults to healthCheckInterval, UnhealthyInterval to half of
+// Interval, Jitter to 0.1 (an explicit 0 disables it) and both thresholds
+// to 1.
+type HealthCheckConfig struct {
+	Interval           time.Duration `yaml:"interval,omitempty"`
+	UnhealthyInterval  time.Duration `yaml:"unhealthyInterval,omitempty"`
+	Jitter             *float64      `yaml:"jitter,omitempty"`
+	HealthyThreshold   int           `yaml:"healthyThreshold,omitempty"`
+	UnhealthyThreshold int           `yaml:"unhealthyThreshold,omitempty"`
+
+	Type           string            `yaml:"type,omitempty"`
+	Path           string            `yaml:"path,omitempty"`
+	Method         string            `yaml:"method,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	Timeout        time.Duration     `yaml:"timeout,omitempty"`
+	ExpectedStatus []string          `yaml:"expectedStatus,omitempty"`
+	BodyContains   string            `yaml:"bodyContains,omitempty"`
+	BodyRegex      string            `yaml:"bodyRegex,omitempty"`
+	Service        string            `yaml:"service,omitempty"`
+	TLS            bool              `yaml:"tls,omitempty"`
+}
+
+// OutlierConfig configures passive health checking from live traffic. A
+// backend is ejected after ConsecutiveFailures failed requests in a row, or
+// when its failure rate exceeds the pool's by more than FailureRateMargin,
+// for BaseEjectionTime doubled on every repeated ejection up to
+// MaxEjectionTime. Zero fields keep the defaults of serverpool.OutlierPolicy.
+type OutlierConfig struct {
+	Enabled             bool          `yaml:"enabled"`
+	Interval            time.Duration `yaml:"interval,omitempty"`
+	ConsecutiveFailures int           `yaml:"consecutiveFailures,omitempty"`
+	FailureRateMargin   float64       `yaml:"failureRateMargin,omitempty"`
+	MinRequests         int           `yaml:"minRequests,omitempty"`
+	BaseEjectionTime    time.Duration `yaml:"baseEjectionTime,omitempty"`
+	MaxEjectionTime     time.Duration `yaml:"maxEjectionTime,omitempty"`
+	MaxEjectionPercent  int           `yaml:"maxEjectionPercent,omitempty"`
+}
+
+// CircuitBreakerConfig configures the circuit breaker of each backend. The
+// circuit opens after FailureThreshold consecutive failures, lets up to
+// HalfOpenRequests trials through after OpenTimeout and closes again after
+// SuccessThreshold successful trials. A zero FailureThreshold disables it.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `yaml:"failureThreshold,omitempty"`
+	OpenTimeout      time.Duration `yaml:"openTimeout,omitempty"`
+	HalfOpenRequests int           `yaml:"halfOpenRequests,omitempty"`
+	SuccessThreshold int           `yaml:"successThreshold,omitempty"`
+}
+
+// RetryConfig controls retrying failed requests on other backends. Requests
+// are tried at most MaxAttempts times in total, so values below 2 disable
+// retries. Idempotent requests are retried on proxy errors and on the RetryOn
+// statuses (502, 503 and 504 by default); with RetryUnsent other requests are
+// retried when no connection to the backend could be made. Requests with a
+// body are only retried when RequestBuffering holds a copy of it.
+type RetryConfig struct {
+	MaxAttempts   int               `yaml:"maxAttempts,omitempty"`
+	PerTryTimeout time.Duration     `yaml:"perTryTimeout,omitempty"`
+	RetryOn       []int             `yaml:"retryOn,omitempty"`
+	RetryUnsent   bool              `yaml:"retryUnsent,omitempty"`
+	Budget        RetryBudgetConfig `yaml:"budget,omitempty"`
+}
+
+// RetryBudgetConfig caps the retries in flight in each pool to Ratio times
+// the pool's requests in flight, always allowing MinRetries. Defaults are 0.2
+// and 3.
+type RetryBudgetConfig struct {
+	Ratio      float64 `yaml:"ratio,omitempty"`
+	MinRetries int     `yaml:"minRetries,omitempty"`
+}
+
+// RequestBufferConfig enables buffering request bodies of up to MaxBytes so
+// they can be replayed by retries. The first MemoryBytes (64KiB by default)
+// are kept in memory and the rest spills to a temporary file in TempDir.
+// Larger bodies are streamed and never retried. A zero MaxBytes disables
+// buffering.
+type RequestBufferConfig struct {
+	MaxBytes    int64  `yaml:"maxBytes,omitempty"`
+	MemoryBytes int64  `yaml:"memoryBytes,omitempty"`
+	TempDir     string `yaml:"tempDir,omitempty"`
+}
+
+// HedgeConfig enables hedging GET and HEAD requests without a body: when
+// the first attempt has not answered within Delay, a second copy is sent to
+// another backend and the first answer wins. Without a Delay the p95 response
+// time of the pool is used, once enough requests have been seen. Budget caps
+// hedges in flight the same way as retries. With retries enabled each attempt
+// is hedged, a failed answer is retried and every hedged copy is bounded by
+// the per-try timeout.
+type HedgeConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	Delay   time.Duration     `yaml:"delay,omitempty"`
+	Budget  RetryBudgetConfig `yaml:"budget,omitempty"`
+}
+
+// SlowStartConfig ramps up the weight of backends that recover or are added
+// at runtime from MinFactor (0.1 by default) to full over Window. Curve is
+// linear (the default) or exponential. A zero Window disables slow start.
+type SlowStartConfig struct {
+	Window    time.Duration `yaml:"window,omitempty"`
+	Curve     string        `yaml:"curve,omitempty"`
+	MinFactor float64       `yaml:"minFactor,omitempty"`
+}
+
+// PoolConfig is a named group of backends that routes send requests to.
+// Strategy defaults to the top level strategy.
+type PoolConfig struct {
+	Name        string                 `yaml:"name"`
+	Strategy    *serverpool.LBStrategy `yaml:"strategy,omitempty"`
+	Backends    []BackendConfig        `yaml:"backends"`
+	HeaderRules HeaderRulesConfig      `yaml:"headerRules,omitempty"`
+}
+
+// RouteConfig sends the requests it matches to Pool, or divides them among
+// the pools of Split by weight. Routes are tried in order and the first match
+// wins. Host may start with a *. wildcard; Path is compared according to
+// PathType, one of prefix (the default), exact or regex. Headers, Query and
+// Cookies must all match. Empty fields match every request. Name identifies
+// the route when adjusting its split at runtime. Mirror copies requests to a
+// shadow pool. HeaderRules apply after those of the pool serving the request.
+type RouteConfig struct {
+	Name        string            `yaml:"name,omitempty"`
+	Host        string            `yaml:"host,omitempty"`
+	Path        string            `yaml:"path,omitempty"`
+	PathType    string            `yaml:"pathType,omitempty"`
+	Methods     []string          `yaml:"methods,omitempty"`
+	Headers     []MatchConfig     `yaml:"headers,omitempty"`
+	Query       []MatchConfig     `yaml:"query,omitempty"`
+	Cookies     []MatchConfig     `yaml:"cookies,omitempty"`
+	Pool        string            `yaml:"pool,omitempty"`
+	Split       []SplitConfig     `yaml:"split,omitempty"`
+	PinBy       PinConfig         `yaml:"pinBy,omitempty"`
+	Mirror      *MirrorConfig     `yaml:"mirror,omitempty"`
+	HeaderRules HeaderRulesConfig `yaml:"headerRules,omitempty"`
+}
+
+// HeaderRulesConfig rewrites the headers of requests before they are sent to
+// a backend, and of responses before they are returned to the client. The
+// top level HeaderRules apply to the top level backends.
+type HeaderRulesConfig struct {
+	Request  HeaderRuleConfig `yaml:"request,omitempty"`
+	Response HeaderRuleConfig `yaml:"response,omitempty"`
+}
+
+// HeaderRuleConfig removes the headers named in Remove, then replaces the
+// values of the Set headers and finally appends the Add headers. Values may
+// refer to {client_ip}, {request_id}, {backend_url}, {host}, {method} and
+// {path}. {request_id} is the X-Request-Id of the request, which is generated
+// and passed on to the backend when missing.
+type HeaderRuleConfig struct {
+	Remove []string       `yaml:"remove,omitempty"`
+	Set    []HeaderConfig `yaml:"set,omitempty"`
+	Add    []HeaderConfig `yaml:"add,omitempty"`
+}
+
+// HeaderConfig is a header name and value
+type HeaderConfig struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// MirrorConfig sends a copy of Percent of the requests of a route to Pool.
+// Mirrored responses are discarded and never delay the client. Requests with
+// a body are only mirrored when RequestBuffering holds a copy of it.
+type MirrorConfig struct {
+	Pool    string  `yaml:"pool"`
+	Percent float64 `yaml:"percent"`
+}
+
+// MirroringConfig bounds the mirrored requests of all routes. At most
+// MaxConcurrent (64 by default) are in flight, further copies being dropped,
+// and each is given up after Timeout (30s by default). Mirrored requests
+// carry the Header (X-Eisodos-Mirror by default) set to 1.
+type MirroringConfig struct {
+	MaxConcurrent int           `yaml:"maxConcurrent,omitempty"`
+	Timeout       time.Duration `yaml:"timeout,omitempty"`
+	Header        string        `yaml:"header,omitempty"`
+}
+
+// SplitConfig sends a share of the traffic of a route to Pool, Weight out of
+// the total weight of the split
+type SplitConfig struct {
+	Pool   string `yaml:"pool"`
+	Weight int    `yaml:"weight"`
+}
+
+// PinConfig keeps a client on one side of a split by hashing the value of a
+// request Header or Cookie. Requests without it are split at random.
+type PinConfig struct {
+	Header string `yaml:"header,omitempty"`
+	Cookie string `yaml:"cookie,omitempty"`
+}
+
+// MatchConfig matches the request header, query parameter or cookie Name.
+// Type is exact (the default), prefix, regex or present, which ignores Value.
+type MatchConfig struct {
+	Name  string `yaml:"name"`
+	Type  string `yaml:"type,omitempty"`
+	Value string `yaml:"value,omitempty"`
+}
+
+// BackendConfig represents a backend server configuration. HealthCheck and
+// CircuitBreaker replace the pool wide settings for this backend.
 type BackendConfig struct {
-	URL      string `yaml:"url"`
-	Weight   int    `yaml:"weight,omitempty"`
-	MaxConns int    `yaml:"maxConns,omitempty"`
+	URL            string                `yaml:"url"`
+	Weight         int                   `yaml:"weight,omitempty"`
+	MaxConns       int                   `yaml:"maxConns,omitempty"`
+	HealthCheck    *HealthCheckConfig    `yaml:"healthCheck,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker,omitempty"`
 }
 
 // DefaultConfig returns a default configuration
@@ -43,11 +310,132 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("health check interval must be positive: %v", c.HealthCheckInterval)
 	}
 
-	if len(c.Backends) == 0 {
+	if c.Queue.Size < 0 {
+		return fmt.Errorf("queue size cannot be negative: %d", c.Queue.Size)
+	}
+
+	if c.Queue.Size > 0 && c.Queue.Timeout <= 0 {
+		return fmt.Errorf("queue timeout must be positive: %v", c.Queue.Timeout)
+	}
+
+	if _, err := serverpool.ParseHashKey(c.Hashing.Key, c.Hashing.Name); err != nil {
+		return fmt.Errorf("invalid hashing configuration: %w", err)
+	}
+
+	if c.Hashing.VirtualNodes < 0 {
+		return fmt.Errorf("virtual nodes cannot be negative: %d", c.Hashing.VirtualNodes)
+	}
+
+	if c.Hashing.Epsilon != nil && *c.Hashing.Epsilon < 0 {
+		return fmt.Errorf("hashing epsilon cannot be negative: %v", *c.Hashing.Epsilon)
+	}
+
+	if _, err := serverpool.ParseTrustedProxies(c.TrustedProxies, c.ClientIPHeader); err != nil {
+		return err
+	}
+
+	if err := c.Sticky.Validate(); err != nil {
+		return fmt.Errorf("invalid sticky sessions configuration: %w", err)
+	}
+
+	if _, err := c.HealthCheck.Checker(); err != nil {
+		return fmt.Errorf("invalid health check: %w", err)
+	}
+
+	if _, err := c.HealthCheck.Policy(); err != nil {
+		return fmt.Errorf("invalid health check: %w", err)
+	}
+
+	if _, err := c.OutlierDetection.Policy(); err != nil {
+		return fmt.Errorf("invalid outlier detection configuration: %w", err)
+	}
+
+	if _, err := c.CircuitBreaker.Policy(); err != nil {
+		return fmt.Errorf("invalid circuit breaker: %w", err)
+	}
+
+	if err := c.Retries.validate(); err != nil {
+		return fmt.Errorf("invalid retries configuration: %w", err)
+	}
+
+	if c.RequestBuffering.MaxBytes < 0 || c.RequestBuffering.MemoryBytes < 0 {
+		return fmt.Errorf("request buffering sizes cannot be negative")
+	}
+
+	if err := c.Hedging.validate(); err != nil {
+		return fmt.Errorf("invalid hedging configuration: %w", err)
+	}
+
+	if _, err := c.SlowStart.Policy(); err != nil {
+		return fmt.Errorf("invalid slow start configuration: %w", err)
+	}
+
+	if c.Mirroring.MaxConcurrent < 0 {
+		return fmt.Errorf("mirroring max concurrent cannot be negative: %d", c.Mirroring.MaxConcurrent)
+	}
+
+	if c.Mirroring.Timeout < 0 {
+		return fmt.Errorf("mirroring timeout cannot be negative: %v", c.Mirroring.Timeout)
+	}
+
+	if err := c.HeaderRules.validate(); err != nil {
+		return fmt.Errorf("invalid header rules: %w", err)
+	}
+
+	backends := len(c.Backends)
+	pools := map[string]bool{DefaultPoolName: true}
+	for i, pool := range c.Pools {
+		if pool.Name == "" {
+			return fmt.Errorf("pool %d: name is required", i)
+		}
+		if pools[pool.Name] {
+			return fmt.Errorf("pool %d: duplicate name %q", i, pool.Name)
+		}
+		pools[pool.Name] = true
+		backends += len(pool.Backends)
+
+		if err := c.validateBackends(pool.Backends); err != nil {
+			return fmt.Errorf("pool %q: %w", pool.Name, err)
+		}
+
+		if err := pool.HeaderRules.validate(); err != nil {
+			return fmt.Errorf("pool %q: invalid header rules: %w", pool.Name, err)
+		}
+	}
+
+	if backends == 0 {
 		return fmt.Errorf("at least one backend is required")
 	}
 
-	for i, backend := range c.Backends {
+	if err := c.validateBackends(c.Backends); err != nil {
+		return err
+	}
+
+	if c.DefaultPool != "" && !pools[c.DefaultPool] {
+		return fmt.Errorf("unknown default pool %q", c.DefaultPool)
+	}
+
+	routes, err := c.RouteTable()
+	if err != nil {
+		return err
+	}
+	for i, route := range routes.Routes() {
+		for _, pool := range route.Pools() {
+			if !pools[pool] {
+				return fmt.Errorf("route %d: unknown pool %q", i, pool)
+			}
+		}
+
+		if err := c.Routes[i].HeaderRules.validate(); err != nil {
+			return fmt.Errorf("route %d: invalid header rules: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateBackends(backends []BackendConfig) error {
+	for i, backend := range backends {
 		if backend.URL == "" {
 			return fmt.Errorf("backend %d: URL is required", i)
 		}
@@ -57,11 +445,319 @@ func (c *Config) Validate() error {
 		if backend.MaxConns < 0 {
 			return fmt.Errorf("backend %d: maxConns cannot be negative", i)
 		}
+
+		if _, err := c.HealthCheckerFor(backend); err != nil {
+			return fmt.Errorf("backend %d: invalid health check: %w", i, err)
+		}
+
+		if _, err := c.HealthPolicyFor(backend); err != nil {
+			return fmt.Errorf("backend %d: invalid health check: %w", i, err)
+		}
+
+		if _, err := c.CircuitBreakerFor(backend); err != nil {
+			return fmt.Errorf("backend %d: invalid circuit breaker: %w", i, err)
+		}
 	}
+	return nil
+}
 
+// RouteTable compiles the routes, without checking that their pools exist
+func (c *Config) RouteTable() (*router.Table, error) {
+	routes := make([]router.Route, len(c.Routes))
+	for i, r := range c.Routes {
+		pathType, err := router.ParsePathType(r.PathType)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+
+		routes[i] = router.Route{
+			Name:     r.Name,
+			Host:     r.Host,
+			Path:     r.Path,
+			PathType: pathType,
+			Methods:  r.Methods,
+			Pool:     r.Pool,
+			PinBy:    router.PinKey{Header: r.PinBy.Header, Cookie: r.PinBy.Cookie},
+		}
+		if r.Mirror != nil {
+			routes[i].Mirror = &router.Mirror{Pool: r.Mirror.Pool, Percent: r.Mirror.Percent}
+		}
+		for _, split := range r.Split {
+			routes[i].Split = append(routes[i].Split, router.WeightedPool{Pool: split.Pool, Weight: split.Weight})
+		}
+
+		if routes[i].Headers, err = valueMatchers(r.Headers); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		if routes[i].Query, err = valueMatchers(r.Query); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		if routes[i].Cookies, err = valueMatchers(r.Cookies); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+	}
+	return router.NewTable(routes)
+}
+
+func (h HeaderRulesConfig) validate() error {
+	if _, err := h.Request.Rules(); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	if _, err := h.Response.Rules(); err != nil {
+		return fmt.Errorf("response: %w", err)
+	}
 	return nil
 }
 
+// Rules compiles the rules, returning nil when there are none
+func (h HeaderRuleConfig) Rules() (*headers.Rules, error) {
+	return headers.NewRules(h.Remove, headerList(h.Set), headerList(h.Add))
+}
+
+func headerList(configs []HeaderConfig) []headers.Header {
+	list := make([]headers.Header, len(configs))
+	for i, h := range configs {
+		list[i] = headers.Header{Name: h.Name, Value: h.Value}
+	}
+	return list
+}
+
+func valueMatchers(matches []MatchConfig) ([]router.ValueMatcher, error) {
+	matchers := make([]router.ValueMatcher, len(matches))
+	for i, m := range matches {
+		matchType, err := router.ParseMatchType(m.Type)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = router.ValueMatcher{Name: m.Name, Type: matchType, Value: m.Value}
+	}
+	return matchers, nil
+}
+
+// Validate checks the sticky session settings. Builders that skip Config
+// validation must still call it, as a short key makes cookies forgeable.
+func (s StickyConfig) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+
+	if len(s.Key) < minStickyKeyLength {
+		return fmt.Errorf("key must be at least %d bytes", minStickyKeyLength)
+	}
+
+	if s.TTL < 0 {
+		return fmt.Errorf("ttl cannot be negative: %v", s.TTL)
+	}
+
+	sameSite, err := serverpool.ParseSameSite(s.SameSite)
+	if err != nil {
+		return err
+	}
+
+	if sameSite == http.SameSiteNoneMode && !s.Secure {
+		return fmt.Errorf("sameSite none requires secure cookies")
+	}
+	return nil
+}
+
+func (r RetryConfig) validate() error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("max attempts cannot be negative: %d", r.MaxAttempts)
+	}
+
+	if r.PerTryTimeout < 0 {
+		return fmt.Errorf("per try timeout cannot be negative: %v", r.PerTryTimeout)
+	}
+
+	for _, status := range r.RetryOn {
+		if status < 400 || status > 599 {
+			return fmt.Errorf("retry status must be in [400, 599]: %d", status)
+		}
+	}
+
+	return r.Budget.validate()
+}
+
+func (h HedgeConfig) validate() error {
+	if h.Delay < 0 {
+		return fmt.Errorf("delay cannot be negative: %v", h.Delay)
+	}
+	return h.Budget.validate()
+}
+
+func (b RetryBudgetConfig) validate() error {
+	if b.Ratio < 0 || b.Ratio > 1 {
+		return fmt.Errorf("budget ratio must be in [0, 1]: %v", b.Ratio)
+	}
+
+	if b.MinRetries < 0 {
+		return fmt.Errorf("budget min retries cannot be negative: %d", b.MinRetries)
+	}
+	return nil
+}
+
+// Policy returns the ejection rules of outlier detection
+func (o OutlierConfig) Policy() (serverpool.OutlierPolicy, error) {
+	if o.Interval < 0 || o.BaseEjectionTime < 0 || o.MaxEjectionTime < 0 {
+		return serverpool.OutlierPolicy{}, fmt.Errorf("durations cannot be negative")
+	}
+
+	if o.ConsecutiveFailures < 0 || o.MinRequests < 0 {
+		return serverpool.OutlierPolicy{}, fmt.Errorf("thresholds cannot be negative")
+	}
+
+	if o.FailureRateMargin < 0 || o.FailureRateMargin >= 1 {
+		return serverpool.OutlierPolicy{}, fmt.Errorf("failure rate margin must be in [0, 1): %v", o.FailureRateMargin)
+	}
+
+	if o.MaxEjectionPercent < 0 || o.MaxEjectionPercent > 100 {
+		return serverpool.OutlierPolicy{}, fmt.Errorf("max ejection percent must be in [0, 100]: %d", o.MaxEjectionPercent)
+	}
+
+	return serverpool.OutlierPolicy{
+		Interval:            o.Interval,
+		ConsecutiveFailures: o.ConsecutiveFailures,
+		FailureRateMargin:   o.FailureRateMargin,
+		MinRequests:         o.MinRequests,
+		BaseEjectionTime:    o.BaseEjectionTime,
+		MaxEjectionTime:     o.MaxEjectionTime,
+		MaxEjectionPercent:  o.MaxEjectionPercent,
+	}, nil
+}
+
+// Policy returns the thresholds of the circuit breaker
+func (cb CircuitBreakerConfig) Policy() (backend.CircuitBreakerPolicy, error) {
+	if cb.FailureThreshold < 0 || cb.HalfOpenRequests < 0 || cb.SuccessThreshold < 0 {
+		return backend.CircuitBreakerPolicy{}, fmt.Errorf("thresholds cannot be negative")
+	}
+
+	if cb.OpenTimeout < 0 {
+		return backend.CircuitBreakerPolicy{}, fmt.Errorf("open timeout cannot be negative: %v", cb.OpenTimeout)
+	}
+
+	return backend.CircuitBreakerPolicy{
+		FailureThreshold: cb.FailureThreshold,
+		OpenTimeout:      cb.OpenTimeout,
+		HalfOpenRequests: cb.HalfOpenRequests,
+		SuccessThreshold: cb.SuccessThreshold,
+	}, nil
+}
+
+// Policy returns the ramp of slow start
+func (s SlowStartConfig) Policy() (backend.SlowStartPolicy, error) {
+	if s.Window < 0 {
+		return backend.SlowStartPolicy{}, fmt.Errorf("window cannot be negative: %v", s.Window)
+	}
+
+	if s.MinFactor < 0 || s.MinFactor > 1 {
+		return backend.SlowStartPolicy{}, fmt.Errorf("min factor must be in [0, 1]: %v", s.MinFactor)
+	}
+
+	curve, err := backend.ParseSlowStartCurve(s.Curve)
+	if err != nil {
+		return backend.SlowStartPolicy{}, err
+	}
+
+	return backend.SlowStartPolicy{
+		Window:    s.Window,
+		Curve:     curve,
+		MinFactor: s.MinFactor,
+	}, nil
+}
+
+// Policy returns the probe schedule and thresholds of the health check
+func (h HealthCheckConfig) Policy() (backend.HealthPolicy, error) {
+	if h.Interval < 0 || h.UnhealthyInterval < 0 {
+		return backend.HealthPolicy{}, fmt.Errorf("intervals cannot be negative")
+	}
+
+	if h.Jitter != nil && (*h.Jitter < 0 || *h.Jitter >= 1) {
+		return backend.HealthPolicy{}, fmt.Errorf("jitter must be in [0, 1): %v", *h.Jitter)
+	}
+
+	if h.HealthyThreshold < 0 || h.UnhealthyThreshold < 0 {
+		return backend.HealthPolicy{}, fmt.Errorf("thresholds cannot be negative")
+	}
+
+	return backend.HealthPolicy{
+		Interval:           h.Interval,
+		UnhealthyInterval:  h.UnhealthyInterval,
+		Jitter:             h.Jitter,
+		HealthyThreshold:   h.HealthyThreshold,
+		UnhealthyThreshold: h.UnhealthyThreshold,
+	}, nil
+}
+
+// Checker builds the backend health checker described by the configuration
+func (h HealthCheckConfig) Checker() (backend.HealthChecker, error) {
+	if h.Timeout < 0 {
+		return nil, fmt.Errorf("timeout cannot be negative: %v", h.Timeout)
+	}
+
+	switch h.Type {
+	case "", "tcp":
+		return &backend.TCPHealthChecker{Timeout: h.Timeout}, nil
+	case "grpc":
+		return &backend.GRPCHealthChecker{Service: h.Service, TLS: h.TLS, Timeout: h.Timeout}, nil
+	case "http":
+	default:
+		return nil, fmt.Errorf("unknown health check type %q", h.Type)
+	}
+
+	checker := &backend.HTTPHealthChecker{
+		Path:         h.Path,
+		Method:       h.Method,
+		Headers:      h.Headers,
+		Timeout:      h.Timeout,
+		BodyContains: h.BodyContains,
+	}
+
+	for _, status := range h.ExpectedStatus {
+		r, err := backend.ParseStatusRange(status)
+		if err != nil {
+			return nil, err
+		}
+		checker.ExpectedStatus = append(checker.ExpectedStatus, r)
+	}
+
+	if h.BodyRegex != "" {
+		re, err := regexp.Compile(h.BodyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body regex: %w", err)
+		}
+		checker.BodyRegex = re
+	}
+
+	return checker, nil
+}
+
+// HealthCheckerFor returns the health checker of b, falling back to the pool
+// wide health check
+func (c *Config) HealthCheckerFor(b BackendConfig) (backend.HealthChecker, error) {
+	if b.HealthCheck != nil {
+		return b.HealthCheck.Checker()
+	}
+	return c.HealthCheck.Checker()
+}
+
+// HealthPolicyFor returns the health policy of b, falling back to the pool
+// wide health check
+func (c *Config) HealthPolicyFor(b BackendConfig) (backend.HealthPolicy, error) {
+	if b.HealthCheck != nil {
+		return b.HealthCheck.Policy()
+	}
+	return c.HealthCheck.Policy()
+}
+
+// CircuitBreakerFor returns the circuit breaker policy of b, falling back to
+// the pool wide circuit breaker
+func (c *Config) CircuitBreakerFor(b BackendConfig) (backend.CircuitBreakerPolicy, error) {
+	if b.CircuitBreaker != nil {
+		return b.CircuitBreaker.Policy()
+	}
+	return c.CircuitBreaker.Policy()
+}
+
 // LoadFromFile loads configuration from a YAML file
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
@@ -6,10 +6,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/darshan-rambhia/eisodos/internal/backend"
 	"github.com/darshan-rambhia/eisodos/internal/serverpool"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
+func ptr[T any](v T) *T {
+	return &v
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 	assert.Equal(t, 8080, cfg.Port)
@@ -123,6 +129,538 @@ func TestConfigValidate(t *testing.T) {
 			wantErr:     true,
 			errContains: "maxConns cannot be negative",
 		},
+		{
+			name: "negative queue size",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Queue:               QueueConfig{Size: -1},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "queue size cannot be negative",
+		},
+		{
+			name: "queue without timeout",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Queue:               QueueConfig{Size: 10},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "queue timeout must be positive",
+		},
+		{
+			name: "hashing header without name",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.ConsistentHash,
+				Hashing:             HashingConfig{Key: "header"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "requires a name",
+		},
+		{
+			name: "negative virtual nodes",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.ConsistentHash,
+				Hashing:             HashingConfig{Key: "path", VirtualNodes: -1},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "virtual nodes cannot be negative",
+		},
+		{
+			name: "negative hashing epsilon",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.BoundedConsistentHash,
+				Hashing:             HashingConfig{Epsilon: ptr(-0.5)},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "hashing epsilon cannot be negative",
+		},
+		{
+			name: "zero hashing epsilon",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.BoundedConsistentHash,
+				Hashing:             HashingConfig{Epsilon: ptr(0.0)},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+		},
+		{
+			name: "invalid trusted proxy",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.IPHash,
+				TrustedProxies:      []string{"10.0.0.0/40"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "invalid trusted proxy",
+		},
+		{
+			name: "unsupported client IP header",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.IPHash,
+				TrustedProxies:      []string{"10.0.0.0/8"},
+				ClientIPHeader:      "X-Real-IP",
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "unsupported client IP header",
+		},
+		{
+			name: "sticky sessions without key",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Sticky:              StickyConfig{Enabled: true, Key: "short"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "key must be at least 16 bytes",
+		},
+		{
+			name: "sticky sessions with SameSite none over plain HTTP",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Sticky:              StickyConfig{Enabled: true, Key: "0123456789abcdef", SameSite: "none"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "sameSite none requires secure cookies",
+		},
+		{
+			name: "unknown health check type",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				HealthCheck:         HealthCheckConfig{Type: "icmp"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "unknown health check type",
+		},
+		{
+			name: "invalid backend health check status",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{
+						URL:         "http://localhost:8081",
+						HealthCheck: &HealthCheckConfig{Type: "http", ExpectedStatus: []string{"2xx", "600"}},
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "backend 0: invalid health check",
+		},
+		{
+			name: "invalid health check body regex",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				HealthCheck:         HealthCheckConfig{Type: "http", BodyRegex: "("},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "invalid body regex",
+		},
+		{
+			name: "health check jitter out of range",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				HealthCheck:         HealthCheckConfig{Jitter: ptr(1.5)},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "jitter must be in [0, 1)",
+		},
+		{
+			name: "outlier failure rate margin out of range",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				OutlierDetection:    OutlierConfig{Enabled: true, FailureRateMargin: 1.5},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "failure rate margin must be in [0, 1)",
+		},
+		{
+			name: "outlier max ejection percent out of range",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				OutlierDetection:    OutlierConfig{Enabled: true, MaxEjectionPercent: 150},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "max ejection percent must be in [0, 100]",
+		},
+		{
+			name: "negative backend circuit breaker threshold",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{
+						URL:            "http://localhost:8081",
+						CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: -1},
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "backend 0: invalid circuit breaker",
+		},
+		{
+			name: "retry status out of range",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Retries:             RetryConfig{MaxAttempts: 3, RetryOn: []int{200}},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "retry status must be in [400, 599]",
+		},
+		{
+			name: "hedge budget ratio out of range",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Hedging:             HedgeConfig{Enabled: true, Budget: RetryBudgetConfig{Ratio: 1.5}},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "invalid hedging configuration: budget ratio must be in [0, 1]",
+		},
+		{
+			name: "unknown slow start curve",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				SlowStart:           SlowStartConfig{Window: time.Minute, Curve: "quadratic"},
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+			},
+			wantErr:     true,
+			errContains: "unknown slow start curve: quadratic",
+		},
+		{
+			name: "named pools without top level backends",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Pools: []PoolConfig{
+					{Name: "api", Backends: []BackendConfig{{URL: "http://localhost:8081"}}},
+				},
+				Routes:      []RouteConfig{{Host: "api.example.com", Pool: "api"}},
+				DefaultPool: "api",
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate pool name",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Pools: []PoolConfig{
+					{Name: "default", Backends: []BackendConfig{{URL: "http://localhost:8081"}}},
+				},
+			},
+			wantErr:     true,
+			errContains: `pool 0: duplicate name "default"`,
+		},
+		{
+			name: "invalid pool backend",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Pools: []PoolConfig{
+					{Name: "api", Backends: []BackendConfig{{URL: "http://localhost:8081", Weight: -1}}},
+				},
+			},
+			wantErr:     true,
+			errContains: `pool "api": backend 0: weight cannot be negative`,
+		},
+		{
+			name: "route to unknown pool",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{Path: "/api", Pool: "api"}},
+			},
+			wantErr:     true,
+			errContains: `route 0: unknown pool "api"`,
+		},
+		{
+			name: "invalid route path regex",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{Path: "(", PathType: "regex", Pool: "default"}},
+			},
+			wantErr:     true,
+			errContains: "route 0: invalid path regex",
+		},
+		{
+			name: "split to unknown pool",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Split: []SplitConfig{{Pool: "default", Weight: 95}, {Pool: "canary", Weight: 5}},
+				}},
+			},
+			wantErr:     true,
+			errContains: `route 0: unknown pool "canary"`,
+		},
+		{
+			name: "route with pool and split",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Pool:  "default",
+					Split: []SplitConfig{{Pool: "default", Weight: 1}},
+				}},
+			},
+			wantErr:     true,
+			errContains: "route 0: pool and split are exclusive",
+		},
+		{
+			name: "invalid pool header rules",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Pools: []PoolConfig{{
+					Name:     "api",
+					Backends: []BackendConfig{{URL: "http://localhost:8081"}},
+					HeaderRules: HeaderRulesConfig{
+						Response: HeaderRuleConfig{Add: []HeaderConfig{{Name: "X-User", Value: "{user}"}}},
+					},
+				}},
+			},
+			wantErr:     true,
+			errContains: `pool "api": invalid header rules: response: add 0: unknown variable {user}`,
+		},
+		{
+			name: "invalid route header rules",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Pool: "default",
+					HeaderRules: HeaderRulesConfig{
+						Request: HeaderRuleConfig{Remove: []string{""}},
+					},
+				}},
+			},
+			wantErr:     true,
+			errContains: "route 0: invalid header rules: request: remove 0: name is required",
+		},
+		{
+			name: "mirror to unknown pool",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Pool:   "default",
+					Mirror: &MirrorConfig{Pool: "shadow", Percent: 10},
+				}},
+			},
+			wantErr:     true,
+			errContains: `route 0: unknown pool "shadow"`,
+		},
+		{
+			name: "invalid mirror percent",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Pool:   "default",
+					Mirror: &MirrorConfig{Pool: "default", Percent: 101},
+				}},
+			},
+			wantErr:     true,
+			errContains: "route 0: mirror percent must be in (0, 100]",
+		},
+		{
+			name: "negative mirroring concurrency",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Mirroring: MirroringConfig{MaxConcurrent: -1},
+			},
+			wantErr:     true,
+			errContains: "mirroring max concurrent cannot be negative",
+		},
+		{
+			name: "duplicate route name",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{
+					{Name: "web", Path: "/web", Pool: "default"},
+					{Name: "web", Pool: "default"},
+				},
+			},
+			wantErr:     true,
+			errContains: `route 1: duplicate name "web"`,
+		},
+		{
+			name: "unknown route header match type",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				Routes: []RouteConfig{{
+					Headers: []MatchConfig{{Name: "X-Tenant", Type: "suffix", Value: "acme"}},
+					Pool:    "default",
+				}},
+			},
+			wantErr:     true,
+			errContains: "route 0: unknown match type: suffix",
+		},
+		{
+			name: "unknown default pool",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{URL: "http://localhost:8081"},
+				},
+				DefaultPool: "web",
+			},
+			wantErr:     true,
+			errContains: `unknown default pool "web"`,
+		},
+		{
+			name: "negative backend health check threshold",
+			config: &Config{
+				Port:                8080,
+				HealthCheckInterval: 10 * time.Second,
+				Strategy:            serverpool.RoundRobin,
+				Backends: []BackendConfig{
+					{
+						URL:         "http://localhost:8081",
+						HealthCheck: &HealthCheckConfig{UnhealthyThreshold: -1},
+					},
+				},
+			},
+			wantErr:     true,
+			errContains: "thresholds cannot be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -285,6 +823,124 @@ func TestLoadFromFileWithInvalidFile(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to parse config file")
 }
 
+func TestHealthCheckerFor(t *testing.T) {
+	cfg := &Config{
+		HealthCheck: HealthCheckConfig{
+			Type:           "http",
+			Path:           "/healthz",
+			ExpectedStatus: []string{"2xx"},
+			BodyRegex:      "ok",
+		},
+	}
+
+	// Backends inherit the pool wide health check
+	checker, err := cfg.HealthCheckerFor(BackendConfig{URL: "http://localhost:8081"})
+	assert.NoError(t, err)
+	if assert.IsType(t, &backend.HTTPHealthChecker{}, checker) {
+		httpChecker := checker.(*backend.HTTPHealthChecker)
+		assert.Equal(t, "/healthz", httpChecker.Path)
+		assert.Equal(t, []backend.StatusRange{{Min: 200, Max: 299}}, httpChecker.ExpectedStatus)
+		assert.NotNil(t, httpChecker.BodyRegex)
+	}
+
+	// A backend level health check replaces it
+	checker, err = cfg.HealthCheckerFor(BackendConfig{
+		URL:         "http://localhost:8082",
+		HealthCheck: &HealthCheckConfig{Type: "tcp", Timeout: time.Second},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &backend.TCPHealthChecker{Timeout: time.Second}, checker)
+
+	// gRPC services are checked through the standard health protocol
+	checker, err = cfg.HealthCheckerFor(BackendConfig{
+		URL:         "http://localhost:50051",
+		HealthCheck: &HealthCheckConfig{Type: "grpc", Service: "orders.v1.Orders", TLS: true},
+	})
+	assert.NoError(t, err)
+	if assert.IsType(t, &backend.GRPCHealthChecker{}, checker) {
+		grpcChecker := checker.(*backend.GRPCHealthChecker)
+		assert.Equal(t, "orders.v1.Orders", grpcChecker.Service)
+		assert.True(t, grpcChecker.TLS)
+	}
+}
+
+func TestHealthPolicyFor(t *testing.T) {
+	cfg := &Config{
+		HealthCheck: HealthCheckConfig{
+			Interval:           5 * time.Second,
+			Jitter:             ptr(0.2),
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 3,
+		},
+	}
+
+	policy, err := cfg.HealthPolicyFor(BackendConfig{URL: "http://localhost:8081"})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.HealthPolicy{
+		Interval:           5 * time.Second,
+		Jitter:             ptr(0.2),
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 3,
+	}, policy)
+
+	policy, err = cfg.HealthPolicyFor(BackendConfig{
+		URL:         "http://localhost:8082",
+		HealthCheck: &HealthCheckConfig{UnhealthyInterval: time.Second},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.HealthPolicy{UnhealthyInterval: time.Second}, policy)
+
+	// An explicit zero jitter is kept so the monitor does not apply its default
+	policy, err = cfg.HealthPolicyFor(BackendConfig{
+		URL:         "http://localhost:8083",
+		HealthCheck: &HealthCheckConfig{Jitter: ptr(0.0)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.HealthPolicy{Jitter: ptr(0.0)}, policy)
+}
+
+func TestCircuitBreakerFor(t *testing.T) {
+	cfg := &Config{
+		CircuitBreaker: CircuitBreakerConfig{FailureThreshold: 5, OpenTimeout: time.Minute},
+	}
+
+	// Backends inherit the pool wide circuit breaker
+	policy, err := cfg.CircuitBreakerFor(BackendConfig{URL: "http://localhost:8081"})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.CircuitBreakerPolicy{FailureThreshold: 5, OpenTimeout: time.Minute}, policy)
+
+	// A backend level circuit breaker replaces it
+	policy, err = cfg.CircuitBreakerFor(BackendConfig{
+		URL:            "http://localhost:8082",
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, HalfOpenRequests: 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, backend.CircuitBreakerPolicy{FailureThreshold: 2, HalfOpenRequests: 3}, policy)
+}
+
+func TestOutlierConfigPolicy(t *testing.T) {
+	cfg := DefaultConfig()
+	err := yaml.Unmarshal([]byte(`
+outlierDetection:
+  enabled: true
+  consecutiveFailures: 3
+  failureRateMargin: 0.2
+  baseEjectionTime: 15s
+  maxEjectionPercent: 50
+`), cfg)
+	assert.NoError(t, err)
+	assert.True(t, cfg.OutlierDetection.Enabled)
+
+	policy, err := cfg.OutlierDetection.Policy()
+	assert.NoError(t, err)
+	assert.Equal(t, serverpool.OutlierPolicy{
+		ConsecutiveFailures: 3,
+		FailureRateMargin:   0.2,
+		BaseEjectionTime:    15 * time.Second,
+		MaxEjectionPercent:  50,
+	}, policy)
+}
+
 func TestLoadFromEnv(t *testing.T) {
 	// Test that LoadFromEnv returns an error when no backends are provided
 	cfg, err := LoadFromEnv()
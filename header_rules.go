@@ -0,0 +1,136 @@
+package eisodos
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/headers"
+)
+
+// requestIDHeader carries the ID that header rules refer to as {request_id}
+const requestIDHeader = "X-Request-Id"
+
+// compileHeaderRules returns the request and response rules of cfg, nil when
+// there are none
+func compileHeaderRules(cfg config.HeaderRulesConfig) (request, response *headers.Rules, err error) {
+	if request, err = cfg.Request.Rules(); err != nil {
+		return nil, nil, err
+	}
+	if response, err = cfg.Response.Rules(); err != nil {
+		return nil, nil, err
+	}
+	return request, response, nil
+}
+
+// withHeaderRules returns a view of np applying the given rules after its
+// own. The view shares the backends and latency of np.
+func (np *namedPool) withHeaderRules(request, response *headers.Rules) *namedPool {
+	view := *np
+	view.requestHeaders = headers.Join(np.requestHeaders, request)
+	view.responseHeaders = headers.Join(np.responseHeaders, response)
+	return &view
+}
+
+// usesRequestID reports whether the header rules of np refer to the request
+// ID
+func (np *namedPool) usesRequestID() bool {
+	return np.requestHeaders.Uses(headers.RequestID) || np.responseHeaders.Uses(headers.RequestID)
+}
+
+// ensureRequestID gives r an X-Request-Id if it has none and the header
+// rules of one of pools need it, so that every attempt at r and its response
+// share the same ID
+func ensureRequestID(r *http.Request, pools ...*namedPool) {
+	if r.Header.Get(requestIDHeader) != "" {
+		return
+	}
+
+	for _, np := range pools {
+		if np != nil && np.usesRequestID() {
+			r.Header.Set(requestIDHeader, newRequestID())
+			return
+		}
+	}
+}
+
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// servePeer serves r on peer, rewriting the request and response headers by
+// the rules of np
+func (lb *LoadBalancer) servePeer(w http.ResponseWriter, r *http.Request, np *namedPool, peer backend.Backend) {
+	if np.requestHeaders == nil && np.responseHeaders == nil {
+		peer.Serve(w, r)
+		return
+	}
+
+	vars := &headers.Vars{
+		ClientIP:   lb.proxies.ClientIP(r),
+		RequestID:  r.Header.Get(requestIDHeader),
+		BackendURL: peer.GetURL().String(),
+		Host:       r.Host,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+
+	if np.requestHeaders != nil {
+		// r may be served again by retries and hedges, so the rules are
+		// applied to a copy
+		r = r.Clone(r.Context())
+		np.requestHeaders.Apply(r.Header, vars)
+
+		// The Host header lives outside of Header
+		if host := r.Header.Get("Host"); host != "" {
+			r.Host = host
+			r.Header.Del("Host")
+		}
+	}
+
+	if np.responseHeaders != nil {
+		w = &headerWriter{ResponseWriter: w, rules: np.responseHeaders, vars: vars}
+	}
+	peer.Serve(w, r)
+}
+
+// headerWriter applies response header rules just before the final status
+// is written
+type headerWriter struct {
+	http.ResponseWriter
+	rules   *headers.Rules
+	vars    *headers.Vars
+	applied bool
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	final := status >= http.StatusOK || status == http.StatusSwitchingProtocols
+	if final && !w.applied {
+		w.rules.Apply(w.Header(), w.vars)
+		w.applied = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerWriter) Write(p []byte) (int, error) {
+	if !w.applied {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// ObserveProxyError passes proxy errors on to the retry and hedging writers
+func (w *headerWriter) ObserveProxyError(err error) {
+	if o, ok := w.ResponseWriter.(backend.ProxyErrorObserver); ok {
+		o.ObserveProxyError(err)
+	}
+}
+
+// Unwrap lets http.ResponseController reach the client connection
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,152 @@
+package eisodos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoUpstream answers with the request headers it received, prefixed with
+// X-Seen-, and a Server header of its own
+func echoUpstream(t *testing.T, name string) *testUpstream {
+	t.Helper()
+
+	return newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range r.Header {
+			w.Header()["X-Seen-"+k] = v
+		}
+		w.Header().Set("X-Seen-Host", r.Host)
+		w.Header().Set("Server", name)
+		w.Write([]byte(name))
+	})
+}
+
+func TestHeaderRules(t *testing.T) {
+	web := echoUpstream(t, "web")
+	api := echoUpstream(t, "api")
+	webURL := mustParseURL(t, web.URL)
+	apiURL := mustParseURL(t, api.URL)
+
+	lb, err := NewLoadBalancerBuilder().
+		WithHealthCheckInterval(time.Hour).
+		WithBackend(webURL, httputil.NewSingleHostReverseProxy(webURL)).
+		WithPool("api", serverpool.RoundRobin).
+		WithPoolBackend("api", apiURL, httputil.NewSingleHostReverseProxy(apiURL)).
+		WithHeaderRules("api", config.HeaderRulesConfig{
+			Request: config.HeaderRuleConfig{
+				Remove: []string{"X-Secret"},
+				Set:    []config.HeaderConfig{{Name: "X-Pool", Value: "api"}},
+			},
+			Response: config.HeaderRuleConfig{
+				Add: []config.HeaderConfig{{Name: "X-Served-By", Value: "{backend_url}"}},
+			},
+		}).
+		WithRoutes([]config.RouteConfig{{
+			Path: "/api",
+			Pool: "api",
+			HeaderRules: config.HeaderRulesConfig{
+				Request: config.HeaderRuleConfig{
+					Set: []config.HeaderConfig{
+						{Name: "X-Pool", Value: "api via route"},
+						{Name: "X-Real-IP", Value: "{client_ip}"},
+						{Name: "X-Original", Value: "{method} {host}{path}"},
+						{Name: "Host", Value: "internal.api"},
+					},
+				},
+				Response: config.HeaderRuleConfig{
+					Remove: []string{"Server"},
+					Set:    []config.HeaderConfig{{Name: "X-Request-Id", Value: "{request_id}"}},
+				},
+			},
+		}}).
+		Build()
+	require.NoError(t, err)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.RemoteAddr = "203.0.113.7:4321"
+		req.Header.Set("X-Secret", "s3cr3t")
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("pool and route rules", func(t *testing.T) {
+		resp := serve("http://shop.example.com/api/orders").Header()
+
+		assert.Empty(t, resp.Get("X-Seen-X-Secret"))
+		assert.Equal(t, "api via route", resp.Get("X-Seen-X-Pool"), "route rules apply after pool rules")
+		assert.Equal(t, "203.0.113.7", resp.Get("X-Seen-X-Real-Ip"))
+		assert.Equal(t, "GET shop.example.com/api/orders", resp.Get("X-Seen-X-Original"))
+		assert.Equal(t, "internal.api", resp.Get("X-Seen-Host"))
+
+		assert.Equal(t, api.URL, resp.Get("X-Served-By"))
+		assert.Empty(t, resp.Get("Server"))
+
+		// The generated request ID reaches the backend and the client
+		assert.Len(t, resp.Get("X-Request-Id"), 32)
+		assert.Equal(t, resp.Get("X-Request-Id"), resp.Get("X-Seen-X-Request-Id"))
+	})
+
+	t.Run("request ID of the client is kept", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://shop.example.com/api/orders", nil)
+		req.Header.Set("X-Request-Id", "from-client")
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, req)
+
+		assert.Equal(t, "from-client", recorder.Header().Get("X-Request-Id"))
+	})
+
+	t.Run("unrouted requests are left alone", func(t *testing.T) {
+		resp := serve("http://shop.example.com/").Header()
+
+		assert.Equal(t, "s3cr3t", resp.Get("X-Seen-X-Secret"))
+		assert.Empty(t, resp.Get("X-Seen-X-Request-Id"))
+		assert.Equal(t, "web", resp.Get("Server"))
+	})
+}
+
+func TestHeaderRulesWithRetries(t *testing.T) {
+	failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+	echo := echoUpstream(t, "echo")
+	lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2}, failing.URL, echo.URL)
+
+	np := lb.pools[config.DefaultPoolName]
+	var err error
+	np.requestHeaders, np.responseHeaders, err = compileHeaderRules(config.HeaderRulesConfig{
+		Request:  config.HeaderRuleConfig{Add: []config.HeaderConfig{{Name: "Via", Value: "eisodos"}}},
+		Response: config.HeaderRuleConfig{Add: []config.HeaderConfig{{Name: "X-Served-By", Value: "{backend_url}"}}},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		// Every attempt starts from the original request
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, []string{"eisodos"}, recorder.Header().Values("X-Seen-Via"))
+		assert.Equal(t, []string{echo.URL}, recorder.Header().Values("X-Served-By"))
+	}
+}
+
+func TestLoadBalancerBuilderHeaderRuleErrors(t *testing.T) {
+	_, err := NewLoadBalancerBuilder().
+		WithHeaderRules("api", config.HeaderRulesConfig{}).
+		Build()
+	assert.ErrorContains(t, err, `header rules set for unknown pool "api"`)
+
+	_, err = NewLoadBalancerBuilder().
+		WithHeaderRules(config.DefaultPoolName, config.HeaderRulesConfig{
+			Request: config.HeaderRuleConfig{Set: []config.HeaderConfig{{Name: "X-User", Value: "{user}"}}},
+		}).
+		Build()
+	assert.ErrorContains(t, err, "unknown variable {user}")
+}
@@ -0,0 +1,354 @@
+package eisodos
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+)
+
+const (
+	// latencyWindowSize is how many recent response times the p95 is taken
+	// over
+	latencyWindowSize = 512
+	// minLatencySamples must be seen before the p95 is used as hedge delay
+	minLatencySamples = 64
+)
+
+// hedgePolicy decides when a request is sent to a second peer
+type hedgePolicy struct {
+	delay  time.Duration
+	budget config.RetryBudgetConfig
+}
+
+func newHedgePolicy(cfg config.HedgeConfig) *hedgePolicy {
+	return &hedgePolicy{
+		delay:  cfg.Delay,
+		budget: cfg.Budget,
+	}
+}
+
+// newBudget creates the hedge budget of one pool
+func (p *hedgePolicy) newBudget() *serverpool.RetryBudget {
+	return serverpool.NewRetryBudget(p.budget.Ratio, p.budget.MinRetries)
+}
+
+// hedgeDelay returns how long to wait for the first attempt before hedging,
+// or false while no delay is configured and too few responses of the pool
+// have been seen to estimate its p95
+func (p *hedgePolicy) hedgeDelay(np *namedPool) (time.Duration, bool) {
+	if p.delay > 0 {
+		return p.delay, true
+	}
+	return np.latency.percentile(0.95, minLatencySamples)
+}
+
+// hedgeable reports whether r is a read that may be sent twice at once
+func hedgeable(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	// Upgrades take over the client connection and cannot be raced
+	if r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody
+}
+
+// serveHedged runs race on r: peer serves it and, if it has not answered
+// within the hedge delay and the budget allows, so does another peer of np
+// not in tried. The first answer is written to the race's writer and the
+// other attempt is cancelled. Each attempt is bounded by timeout if set.
+func (lb *LoadBalancer) serveHedged(race *hedgeRace, r *http.Request, np *namedPool, peer backend.Backend, tried []backend.Backend, timeout time.Duration) {
+	budget := np.hedgeBudget
+	budget.Begin()
+	defer budget.End()
+
+	race.launch(lb, r, np, peer, timeout)
+	hedged := lb.hedgeIfSlow(race, r, np, peer, tried, timeout)
+	race.wait()
+
+	if hedged {
+		budget.Release()
+	}
+
+	// The winner's response copy failed; let the HTTP server abort the
+	// client connection as it would without hedging
+	if race.aborted {
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// hedgeIfSlow launches a second attempt once the hedge delay has passed
+// without an answer. It reports whether a hedge was reserved from the budget.
+func (lb *LoadBalancer) hedgeIfSlow(race *hedgeRace, r *http.Request, np *namedPool, peer backend.Backend, tried []backend.Backend, timeout time.Duration) bool {
+	delay, ok := lb.hedge.hedgeDelay(np)
+	if !ok {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-race.answered:
+		return false
+	case <-r.Context().Done():
+		return false
+	case <-timer.C:
+	}
+
+	next := serverpool.NextUntriedPeer(np.pool, r, tried)
+	if next == nil || !np.hedgeBudget.Acquire() {
+		return false
+	}
+
+	if race.launch(lb, r, np, next, timeout) {
+		slog.Debug(
+			"Hedging request",
+			"URL", r.URL.String(),
+			"from", peer.GetURL().String(),
+			"to", next.GetURL().String(),
+			"delay", delay,
+		)
+	}
+	return true
+}
+
+// hedgeRace collects the attempts at one request. The first attempt to
+// answer wins and writes to the client; failed answers only win when no
+// other attempt is still pending.
+type hedgeRace struct {
+	rw       http.ResponseWriter
+	answered chan struct{}
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	winner  *hedgeWriter
+	pending int
+	cancels map[*hedgeWriter]context.CancelFunc
+	peers   []backend.Backend
+	aborted bool
+}
+
+func newHedgeRace(w http.ResponseWriter) *hedgeRace {
+	return &hedgeRace{
+		rw:       w,
+		answered: make(chan struct{}),
+		cancels:  make(map[*hedgeWriter]context.CancelFunc),
+	}
+}
+
+// launch starts an attempt at r on peer of np, bounded by timeout if set,
+// unless the race has been decided
+func (race *hedgeRace) launch(lb *LoadBalancer, r *http.Request, np *namedPool, peer backend.Backend, timeout time.Duration) bool {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	if race.winner != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	hw := &hedgeWriter{race: race, header: make(http.Header), start: time.Now()}
+	race.cancels[hw] = cancel
+	race.pending++
+	race.peers = append(race.peers, peer)
+
+	race.wg.Add(1)
+	go func() {
+		defer race.wg.Done()
+		defer cancel()
+		defer func() {
+			// ReverseProxy aborts failed response copies with a panic that
+			// only the HTTP server recovers, and only on the handler
+			// goroutine. A loser's abort is dropped; the winner's is raised
+			// again by serveHedged once every attempt has returned.
+			if err := recover(); err != nil {
+				if err != http.ErrAbortHandler {
+					panic(err)
+				}
+				race.mu.Lock()
+				race.aborted = race.aborted || hw.won
+				race.mu.Unlock()
+			}
+		}()
+
+		lb.serveAttempt(hw, r.WithContext(ctx), np, peer, timeout)
+		if !hw.decided {
+			hw.WriteHeader(http.StatusOK)
+		}
+
+		// Every attempt that got an answer counts, losers included, so
+		// that the p95 is not biased towards the fastest peers
+		if hw.err == nil {
+			np.latency.observe(hw.elapsed)
+		}
+	}()
+	return true
+}
+
+// launched returns the peers the race has sent attempts to
+func (race *hedgeRace) launched() []backend.Backend {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	return slices.Clone(race.peers)
+}
+
+// wait blocks until every attempt has returned, after which the client
+// writer is no longer used
+func (race *hedgeRace) wait() {
+	race.wg.Wait()
+}
+
+// claim decides the race for hw if it answered first. The other attempts are
+// cancelled.
+func (race *hedgeRace) claim(hw *hedgeWriter, status int) bool {
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	race.pending--
+	if race.winner != nil {
+		return false
+	}
+
+	failed := status >= http.StatusInternalServerError || hw.err != nil
+	if failed && race.pending > 0 {
+		return false
+	}
+
+	race.winner = hw
+	close(race.answered)
+	for other, cancel := range race.cancels {
+		if other != hw {
+			cancel()
+		}
+	}
+	return true
+}
+
+// hedgeWriter holds back the response of an attempt until it has claimed the
+// race. Losing responses are discarded.
+type hedgeWriter struct {
+	race   *hedgeRace
+	header http.Header
+	start  time.Time
+
+	elapsed time.Duration
+	err     error
+	decided bool
+	won     bool
+}
+
+func (w *hedgeWriter) Header() http.Header {
+	if w.won {
+		return w.race.rw.Header()
+	}
+	return w.header
+}
+
+func (w *hedgeWriter) WriteHeader(status int) {
+	// Informational responses of a racing attempt are dropped
+	if w.decided || status < 200 {
+		return
+	}
+
+	w.decided = true
+	w.elapsed = time.Since(w.start)
+	if !w.race.claim(w, status) {
+		return
+	}
+
+	w.won = true
+	if o, ok := w.race.rw.(backend.ProxyErrorObserver); ok && w.err != nil {
+		o.ObserveProxyError(w.err)
+	}
+	copyHeader(w.race.rw.Header(), w.header)
+	w.race.rw.WriteHeader(status)
+}
+
+func (w *hedgeWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.won {
+		return len(p), nil
+	}
+	return w.race.rw.Write(p)
+}
+
+// ObserveProxyError records that the backend failed to respond, so that the
+// error response does not win while another attempt may still succeed
+func (w *hedgeWriter) ObserveProxyError(err error) {
+	w.err = err
+}
+
+// Flush only reaches the client once the attempt has won
+func (w *hedgeWriter) Flush() {
+	if w.won {
+		http.NewResponseController(w.race.rw).Flush()
+	}
+}
+
+// latencyWindow keeps the most recent response times to estimate
+// percentiles of a pool. Estimates are cached and refreshed every
+// latencyRefresh observations.
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+
+	sorted []time.Duration
+	stale  int
+}
+
+// latencyRefresh is how many observations a cached estimate survives
+const latencyRefresh = 32
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+func (l *latencyWindow) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.samples[l.next] = d
+	l.next++
+	if l.next == len(l.samples) {
+		l.next = 0
+		l.full = true
+	}
+	l.stale++
+}
+
+// percentile returns the q quantile of the window, or false while it holds
+// fewer than minSamples
+func (l *latencyWindow) percentile(q float64, minSamples int) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	if n == 0 || n < minSamples {
+		return 0, false
+	}
+
+	if l.sorted == nil || l.stale >= latencyRefresh {
+		l.sorted = append(l.sorted[:0], l.samples[:n]...)
+		slices.Sort(l.sorted)
+		l.stale = 0
+	}
+	return l.sorted[min(int(q*float64(len(l.sorted))), len(l.sorted)-1)], true
+}
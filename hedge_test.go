@@ -0,0 +1,235 @@
+package eisodos
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHedgingLoadBalancer builds a round-robin load balancer hedging after
+// delay
+func newHedgingLoadBalancer(t *testing.T, hedging config.HedgeConfig, upstreams ...string) *LoadBalancer {
+	t.Helper()
+
+	lb, err := newTestBuilder(t, upstreams...).WithHedging(hedging).Build()
+	require.NoError(t, err)
+	return lb
+}
+
+// slowUpstream answers after delay unless the request is cancelled first
+func slowUpstream(t *testing.T, delay time.Duration, cancelled *atomic.Int32) *testUpstream {
+	t.Helper()
+
+	return newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			cancelled.Add(1)
+		case <-time.After(delay):
+			respond(http.StatusOK, "slow")(w, r)
+		}
+	})
+}
+
+func TestHedging(t *testing.T) {
+	t.Run("slow request answered by the hedge", func(t *testing.T) {
+		var cancelled atomic.Int32
+		slow := slowUpstream(t, time.Second, &cancelled)
+		fast := newTestUpstream(t, respond(http.StatusOK, "fast"))
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{Enabled: true, Delay: 20 * time.Millisecond}, slow.URL, fast.URL)
+
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			start := time.Now()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "fast", recorder.Body.String())
+			assert.Equal(t, "fast", recorder.Header().Get("X-Upstream"))
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		}
+
+		assert.Equal(t, int32(2), fast.hits.Load())
+		assert.Eventually(t, func() bool {
+			return cancelled.Load() == slow.hits.Load()
+		}, time.Second, 10*time.Millisecond, "the losing attempt was not cancelled")
+	})
+
+	t.Run("fast request is not hedged", func(t *testing.T) {
+		a := newTestUpstream(t, respond(http.StatusOK, "a"))
+		b := newTestUpstream(t, respond(http.StatusOK, "b"))
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{Enabled: true, Delay: time.Second}, a.URL, b.URL)
+
+		for i := 0; i < 4; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+		assert.Equal(t, int32(4), a.hits.Load()+b.hits.Load())
+	})
+
+	t.Run("failed hedge does not beat a pending attempt", func(t *testing.T) {
+		var cancelled atomic.Int32
+		slow := slowUpstream(t, 100*time.Millisecond, &cancelled)
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond}, slow.URL, failing.URL)
+
+		codes := map[int]int{}
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			codes[recorder.Code]++
+		}
+
+		// A failure answering first with nothing else in flight is returned
+		assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusServiceUnavailable: 1}, codes)
+		assert.Equal(t, int32(0), cancelled.Load())
+	})
+
+	t.Run("requests other than reads are not hedged", func(t *testing.T) {
+		var cancelled atomic.Int32
+		slow := slowUpstream(t, 100*time.Millisecond, &cancelled)
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{Enabled: true, Delay: 10 * time.Millisecond}, slow.URL, slow.URL)
+
+		for _, r := range []*http.Request{
+			httptest.NewRequest("POST", "/", nil),
+			httptest.NewRequest("GET", "/", strings.NewReader("payload")),
+		} {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, r)
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+		assert.Equal(t, int32(2), slow.hits.Load())
+	})
+
+	t.Run("hedges stop when the budget is spent", func(t *testing.T) {
+		var cancelled atomic.Int32
+		slow := slowUpstream(t, 100*time.Millisecond, &cancelled)
+		fast := newTestUpstream(t, respond(http.StatusOK, "fast"))
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{
+			Enabled: true,
+			Delay:   10 * time.Millisecond,
+			Budget:  config.RetryBudgetConfig{Ratio: 0.1, MinRetries: 1},
+		}, slow.URL, fast.URL)
+
+		// Hold the only hedge the budget allows
+		budget := lb.pools[config.DefaultPoolName].hedgeBudget
+		require.True(t, budget.Acquire())
+
+		bodies := map[string]int{}
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			bodies[recorder.Body.String()]++
+		}
+		assert.Equal(t, map[string]int{"slow": 1, "fast": 1}, bodies)
+
+		budget.Release()
+	})
+
+	t.Run("failed races are retried", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		ok := newTestUpstream(t, respond(http.StatusOK, "ok"))
+		lb, err := newTestBuilder(t, failing.URL, ok.URL).
+			WithRetries(config.RetryConfig{MaxAttempts: 2}).
+			WithHedging(config.HedgeConfig{Enabled: true, Delay: time.Second}).
+			Build()
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "ok", recorder.Body.String())
+		}
+		assert.Equal(t, int32(1), failing.hits.Load())
+	})
+
+	t.Run("hedged attempts keep the per-try timeout", func(t *testing.T) {
+		var cancelled atomic.Int32
+		slow := slowUpstream(t, time.Second, &cancelled)
+		fast := newTestUpstream(t, respond(http.StatusOK, "fast"))
+		lb, err := newTestBuilder(t, slow.URL, fast.URL).
+			WithRetries(config.RetryConfig{MaxAttempts: 2, PerTryTimeout: 50 * time.Millisecond}).
+			WithHedging(config.HedgeConfig{Enabled: true, Delay: time.Second}).
+			Build()
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			start := time.Now()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, "fast", recorder.Body.String())
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		}
+		assert.Equal(t, int32(1), slow.hits.Load())
+	})
+
+	t.Run("aborted responses reach the HTTP server", func(t *testing.T) {
+		// The upstream breaks off its response half way through the body
+		truncating := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			w.Write([]byte("partial"))
+			http.NewResponseController(w).Flush()
+			panic(http.ErrAbortHandler)
+		})
+		lb := newHedgingLoadBalancer(t, config.HedgeConfig{Enabled: true, Delay: time.Second}, truncating.URL)
+
+		// Only a real server makes ReverseProxy abort with a panic, which
+		// must not escape the attempt's goroutine
+		server := httptest.NewServer(lb)
+		t.Cleanup(server.Close)
+
+		for i := 0; i < 2; i++ {
+			resp, err := http.Get(server.URL)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			assert.Error(t, err, "the truncated response was not aborted")
+		}
+	})
+}
+
+func TestHedgeDelay(t *testing.T) {
+	p := newHedgePolicy(config.HedgeConfig{Enabled: true})
+	np := newNamedPool(config.DefaultPoolName, nil)
+
+	_, ok := p.hedgeDelay(np)
+	assert.False(t, ok, "hedged without any latency samples")
+
+	for i := 1; i <= 100; i++ {
+		np.latency.observe(time.Duration(i) * time.Millisecond)
+	}
+	delay, ok := p.hedgeDelay(np)
+	require.True(t, ok)
+	assert.Equal(t, 96*time.Millisecond, delay)
+
+	p = newHedgePolicy(config.HedgeConfig{Enabled: true, Delay: 5 * time.Millisecond})
+	delay, ok = p.hedgeDelay(np)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Millisecond, delay)
+}
+
+func TestLatencyWindow(t *testing.T) {
+	l := newLatencyWindow(4)
+	for _, ms := range []int{10, 20, 30, 40, 50, 60} {
+		l.observe(time.Duration(ms) * time.Millisecond)
+	}
+
+	// Only the last four samples are kept
+	median, ok := l.percentile(0.5, 1)
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, median)
+
+	_, ok = l.percentile(0.5, 5)
+	assert.False(t, ok)
+}
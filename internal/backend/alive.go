@@ -3,18 +3,24 @@ package backend
 import (
 	"context"
 	"log/slog"
-	"net"
 	"net/url"
 )
 
 func IsBackendAlive(ctx context.Context, aliveChannel chan bool, u *url.URL) {
-	var d net.Dialer
-	conn, err := d.DialContext(ctx, "tcp", u.Host)
-	if err != nil {
+	CheckBackendAlive(ctx, aliveChannel, &TCPHealthChecker{}, u)
+}
+
+// CheckBackendAlive runs checker against u and reports the outcome on
+// aliveChannel. A nil checker falls back to a TCP connect.
+func CheckBackendAlive(ctx context.Context, aliveChannel chan bool, checker HealthChecker, u *url.URL) {
+	if checker == nil {
+		checker = &TCPHealthChecker{}
+	}
+
+	if err := checker.Check(ctx, u); err != nil {
 		slog.Debug("Site unreachable", "error", err)
 		aliveChannel <- false
 		return
 	}
-	_ = conn.Close()
 	aliveChannel <- true
 }
@@ -1,9 +1,12 @@
 package backend
 
 import (
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"sync/atomic"
+	"time"
 )
 
 type Backend interface {
@@ -11,45 +14,255 @@ type Backend interface {
 	IsAlive() bool
 	GetURL() *url.URL
 	GetActiveConnections() int
+	GetWeight() int
+	IsSaturated() bool
+	GetLatency() time.Duration
+	GetHealthChecker() HealthChecker
+	GetHealthPolicy() HealthPolicy
+	GetOutcomes() Outcomes
+	SetEjected(bool)
+	IsEjected() bool
+	GetCircuitState() CircuitState
+	IsCircuitOpen() bool
+	StartSlowStart()
+	GetSlowStartFactor() float64
 	Serve(http.ResponseWriter, *http.Request)
 }
 
+// Option configures optional backend settings
+type Option func(*backend)
+
+// WithWeight sets the relative weight used by weighted strategies.
+// Weights below 1 are treated as 1.
+func WithWeight(weight int) Option {
+	return func(b *backend) {
+		if weight > 0 {
+			b.weight = weight
+		}
+	}
+}
+
+// WithMaxConns caps the number of requests the backend serves concurrently.
+// Zero means unlimited.
+func WithMaxConns(maxConns int) Option {
+	return func(b *backend) {
+		if maxConns > 0 {
+			b.maxConns = int64(maxConns)
+		}
+	}
+}
+
+// WithLatencyDecay sets the time constant of the response latency average
+func WithLatencyDecay(decay time.Duration) Option {
+	return func(b *backend) {
+		if decay > 0 {
+			b.latency.decay = decay
+		}
+	}
+}
+
+// WithHealthChecker sets how health checks probe the backend. Without one a
+// TCP connect is used.
+func WithHealthChecker(checker HealthChecker) Option {
+	return func(b *backend) {
+		b.healthChecker = checker
+	}
+}
+
+// WithHealthPolicy sets the probe schedule and state change thresholds
+func WithHealthPolicy(policy HealthPolicy) Option {
+	return func(b *backend) {
+		b.healthPolicy = policy
+	}
+}
+
+// WithCircuitBreaker stops sending requests to the backend after a run of
+// failures until trial requests show it has recovered
+func WithCircuitBreaker(policy CircuitBreakerPolicy) Option {
+	return func(b *backend) {
+		b.breaker = newCircuitBreaker(policy)
+	}
+}
+
+// WithSlowStart ramps up the traffic of the backend after it recovers or is
+// added to a running load balancer
+func WithSlowStart(policy SlowStartPolicy) Option {
+	return func(b *backend) {
+		b.slowStart = newSlowStart(policy)
+	}
+}
+
 type backend struct {
-	url          *url.URL
-	alive        chan bool
-	connections  chan int
-	reverseProxy *httputil.ReverseProxy
+	url           *url.URL
+	alive         atomic.Bool
+	connections   atomic.Int64
+	weight        int
+	maxConns      int64
+	latency       peakEWMA
+	healthChecker HealthChecker
+	healthPolicy  HealthPolicy
+	outcomes      outcomeCounters
+	ejected       atomic.Bool
+	breaker       *circuitBreaker
+	slowStart     *slowStart
+	reverseProxy  *httputil.ReverseProxy
 }
 
 func (b *backend) GetActiveConnections() int {
-	return <-b.connections
+	return int(b.connections.Load())
 }
 
+// SetAlive records the result of health checks. A backend coming back up
+// starts its slow start ramp.
 func (b *backend) SetAlive(alive bool) {
-	b.alive <- alive
+	if !b.alive.Swap(alive) && alive {
+		b.slowStart.start(time.Now())
+	}
 }
 
 func (b *backend) IsAlive() bool {
-	return <-b.alive
+	return b.alive.Load()
 }
 
 func (b *backend) GetURL() *url.URL {
 	return b.url
 }
 
+func (b *backend) GetWeight() int {
+	return b.weight
+}
+
+func (b *backend) IsSaturated() bool {
+	return b.maxConns > 0 && b.connections.Load() >= b.maxConns
+}
+
+// GetLatency returns the peak EWMA of response latency, decayed towards zero
+// while the backend receives no traffic, or zero before the first response
+func (b *backend) GetLatency() time.Duration {
+	return b.latency.get(time.Now())
+}
+
+func (b *backend) GetHealthChecker() HealthChecker {
+	return b.healthChecker
+}
+
+func (b *backend) GetHealthPolicy() HealthPolicy {
+	return b.healthPolicy
+}
+
+// GetOutcomes returns the results of the requests served so far
+func (b *backend) GetOutcomes() Outcomes {
+	return b.outcomes.get()
+}
+
+// SetEjected takes the backend out of rotation, or returns it, independently
+// of the alive state maintained by active health checks
+func (b *backend) SetEjected(ejected bool) {
+	b.ejected.Store(ejected)
+}
+
+func (b *backend) IsEjected() bool {
+	return b.ejected.Load()
+}
+
+// GetCircuitState returns the state of the circuit breaker, which is always
+// closed when none is configured
+func (b *backend) GetCircuitState() CircuitState {
+	return b.breaker.get(time.Now())
+}
+
+// IsCircuitOpen reports whether the circuit breaker would refuse a request
+// right now, including a half-open circuit with all its trials in flight
+func (b *backend) IsCircuitOpen() bool {
+	return b.breaker.refusing(time.Now())
+}
+
+// StartSlowStart restarts the slow start ramp, if one is configured
+func (b *backend) StartSlowStart() {
+	b.slowStart.start(time.Now())
+}
+
+// GetSlowStartFactor returns the fraction of its weight the backend should
+// get while ramping up, which is 1 once the ramp is over
+func (b *backend) GetSlowStartFactor() float64 {
+	return b.slowStart.factor(time.Now())
+}
+
+// acquire reserves a connection slot, failing when maxConns is reached
+func (b *backend) acquire() bool {
+	for {
+		current := b.connections.Load()
+		if b.maxConns > 0 && current >= b.maxConns {
+			return false
+		}
+		if b.connections.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
 func (b *backend) Serve(rw http.ResponseWriter, req *http.Request) {
-	b.connections <- (<-b.connections + 1)
-	defer func() {
-		b.connections <- (<-b.connections - 1)
-	}()
-	b.reverseProxy.ServeHTTP(rw, req)
+	if !b.acquire() {
+		http.Error(rw, "Backend at capacity", http.StatusServiceUnavailable)
+		return
+	}
+	defer b.connections.Add(-1)
+
+	start := time.Now()
+	ok, trial := b.breaker.allow(start)
+	if !ok {
+		http.Error(rw, "Backend circuit open", http.StatusServiceUnavailable)
+		return
+	}
+
+	recorder := &statusRecorder{ResponseWriter: rw}
+	b.reverseProxy.ServeHTTP(recorder, req)
+
+	now := time.Now()
+	b.latency.observe(now.Sub(start), now)
+
+	o := classify(req, recorder.status)
+	b.outcomes.record(o)
+	b.breaker.done(trial, o, now)
 }
 
-func NewBackend(u *url.URL, rp *httputil.ReverseProxy) Backend {
-	return &backend{
+// NewBackend creates a backend that is considered alive until a health check
+// reports otherwise. The error handler of rp is wrapped so that proxy errors
+// reach writers implementing ProxyErrorObserver.
+func NewBackend(u *url.URL, rp *httputil.ReverseProxy, opts ...Option) Backend {
+	if rp != nil {
+		rp.ErrorHandler = observeProxyErrors(rp.ErrorHandler)
+	}
+
+	b := &backend{
 		url:          u,
-		alive:        make(chan bool, 1),
-		connections:  make(chan int, 1),
+		weight:       1,
+		latency:      peakEWMA{decay: defaultLatencyDecay},
 		reverseProxy: rp,
 	}
+	b.alive.Store(true)
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// observeProxyErrors reports errors to the statusRecorder of Serve before
+// handling them as before, falling back to the default of ReverseProxy
+func observeProxyErrors(handler func(http.ResponseWriter, *http.Request, error)) func(http.ResponseWriter, *http.Request, error) {
+	if handler == nil {
+		handler = func(rw http.ResponseWriter, req *http.Request, err error) {
+			slog.Error("Proxy error", "URL", req.URL.String(), "error", err)
+			rw.WriteHeader(http.StatusBadGateway)
+		}
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request, err error) {
+		if recorder, ok := rw.(*statusRecorder); ok {
+			recorder.observeProxyError(err)
+		}
+		handler(rw, req, err)
+	}
 }
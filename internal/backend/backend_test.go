@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
@@ -34,8 +35,6 @@ func TestBackend_IsAlive(t *testing.T) {
 			b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
 			backend := b.(*backend)
 
-			// Initialize the channels
-			backend.connections <- 0
 			backend.SetAlive(tt.setAlive)
 
 			// Test IsAlive
@@ -60,8 +59,6 @@ func TestBackend_GetActiveConnections(t *testing.T) {
 	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
 	backend := b.(*backend)
 
-	// Initialize the connections channel
-	backend.connections <- 0
 	if got := backend.GetActiveConnections(); got != 0 {
 		t.Errorf("Backend.GetActiveConnections() = %v, want 0", got)
 	}
@@ -83,8 +80,6 @@ func TestBackend_ConnectionManagement(t *testing.T) {
 	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
 	backend := b.(*backend)
 
-	// Initialize the channels
-	backend.connections <- 0
 	backend.SetAlive(true)
 
 	// Test serving request
@@ -113,8 +108,6 @@ func TestBackend_HealthCheck(t *testing.T) {
 	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
 	backend := b.(*backend)
 
-	// Initialize the channels
-	backend.connections <- 0
 	backend.SetAlive(false)
 
 	// Test health check
@@ -144,8 +137,6 @@ func TestBackend_Serve(t *testing.T) {
 	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
 	backend := b.(*backend)
 
-	// Initialize the channels
-	backend.connections <- 0
 	backend.SetAlive(true)
 
 	// Test serving request
@@ -159,4 +150,134 @@ func TestBackend_Serve(t *testing.T) {
 	if recorder.Body.String() != "test response" {
 		t.Errorf("Backend.Serve() body = %v, want %v", recorder.Body.String(), "test response")
 	}
+
+	if backend.GetLatency() <= 0 {
+		t.Errorf("Backend.GetLatency() = %v after serving, want a positive latency", backend.GetLatency())
+	}
+}
+
+func TestBackend_GetWeight(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want int
+	}{
+		{
+			name: "default weight",
+			want: 1,
+		},
+		{
+			name: "explicit weight",
+			opts: []Option{WithWeight(3)},
+			want: 3,
+		},
+		{
+			name: "zero weight falls back to default",
+			opts: []Option{WithWeight(0)},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURL, _ := url.Parse("http://test.com")
+			b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL), tt.opts...)
+
+			if got := b.GetWeight(); got != tt.want {
+				t.Errorf("Backend.GetWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackend_MaxConns(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+
+	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL), WithMaxConns(1))
+	if b.IsSaturated() {
+		t.Fatal("Backend should not be saturated before serving")
+	}
+
+	// Occupy the only slot
+	done := make(chan struct{})
+	go func() {
+		b.Serve(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		close(done)
+	}()
+	<-started
+
+	if !b.IsSaturated() {
+		t.Error("Backend should be saturated at maxConns")
+	}
+
+	// A second request is refused without reaching the upstream
+	recorder := httptest.NewRecorder()
+	b.Serve(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Backend.Serve() status code = %v, want %v", recorder.Code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	<-done
+
+	if b.IsSaturated() {
+		t.Error("Backend should not be saturated after the request completes")
+	}
+	if got := b.GetActiveConnections(); got != 0 {
+		t.Errorf("Backend.GetActiveConnections() = %v, want 0", got)
+	}
+}
+
+func TestBackend_Outcomes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL))
+
+	for _, path := range []string{"/", "/missing", "/error", "/error"} {
+		b.Serve(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	want := Outcomes{Requests: 4, Failures: 2, ConsecutiveFailures: 2}
+	if got := b.GetOutcomes(); got != want {
+		t.Errorf("Backend.GetOutcomes() = %+v, want %+v", got, want)
+	}
+
+	// Connect errors count as failures
+	server.Close()
+	b.Serve(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got := b.GetOutcomes().ConsecutiveFailures; got != 3 {
+		t.Errorf("ConsecutiveFailures = %v after a connect error, want 3", got)
+	}
+
+	// Requests abandoned by the client do not
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Serve(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	want = Outcomes{Requests: 5, Failures: 3, ConsecutiveFailures: 3}
+	if got := b.GetOutcomes(); got != want {
+		t.Errorf("Backend.GetOutcomes() = %+v after a cancelled request, want %+v", got, want)
+	}
 }
@@ -0,0 +1,194 @@
+package backend
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a backend's circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen refuses requests until the open timeout has passed
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through to
+	// decide whether the backend has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerPolicy controls when a backend's circuit opens and how it
+// recovers. A zero FailureThreshold disables the breaker; other zero fields
+// fall back to the defaults noted on each field.
+type CircuitBreakerPolicy struct {
+	// FailureThreshold consecutive failures open the circuit
+	FailureThreshold int
+	// OpenTimeout before an open circuit lets trial requests through.
+	// Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is how many trial requests may be in flight at once.
+	// Defaults to 1.
+	HalfOpenRequests int
+	// SuccessThreshold consecutive successful trials close the circuit.
+	// Defaults to 1.
+	SuccessThreshold int
+}
+
+const defaultOpenTimeout = 30 * time.Second
+
+// circuitBreaker tracks the results of requests to one backend. A nil
+// breaker is always closed.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	policy    CircuitBreakerPolicy
+	state     CircuitState
+	failures  int
+	successes int
+	trials    int
+	openedAt  time.Time
+}
+
+func newCircuitBreaker(policy CircuitBreakerPolicy) *circuitBreaker {
+	if policy.FailureThreshold <= 0 {
+		return nil
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = defaultOpenTimeout
+	}
+	if policy.HalfOpenRequests <= 0 {
+		policy.HalfOpenRequests = 1
+	}
+	if policy.SuccessThreshold <= 0 {
+		policy.SuccessThreshold = 1
+	}
+	return &circuitBreaker{policy: policy}
+}
+
+// current returns the state at now, moving an open circuit to half-open once
+// its timeout has passed. Callers must hold the lock.
+func (cb *circuitBreaker) current(now time.Time) CircuitState {
+	if cb.state == CircuitOpen && now.Sub(cb.openedAt) >= cb.policy.OpenTimeout {
+		cb.state = CircuitHalfOpen
+		cb.successes = 0
+		cb.trials = 0
+	}
+	return cb.state
+}
+
+func (cb *circuitBreaker) get(now time.Time) CircuitState {
+	if cb == nil {
+		return CircuitClosed
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.current(now)
+}
+
+// refusing reports whether a request sent now would be refused, either
+// because the circuit is open or because every trial slot is taken
+func (cb *circuitBreaker) refusing(now time.Time) bool {
+	if cb == nil {
+		return false
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.current(now) {
+	case CircuitOpen:
+		return true
+	case CircuitHalfOpen:
+		return cb.trials >= cb.policy.HalfOpenRequests
+	default:
+		return false
+	}
+}
+
+// allow admits a request, reporting whether it was admitted and whether it
+// is a half-open trial
+func (cb *circuitBreaker) allow(now time.Time) (ok, trial bool) {
+	if cb == nil {
+		return true, false
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.current(now) {
+	case CircuitOpen:
+		return false, false
+	case CircuitHalfOpen:
+		if cb.trials >= cb.policy.HalfOpenRequests {
+			return false, false
+		}
+		cb.trials++
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// done records the outcome of a request admitted by allow
+func (cb *circuitBreaker) done(trial bool, o outcome, now time.Time) {
+	if cb == nil {
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if trial {
+		cb.trials--
+	}
+
+	switch cb.current(now) {
+	case CircuitClosed:
+		switch o {
+		case outcomeFailure:
+			cb.failures++
+			if cb.failures >= cb.policy.FailureThreshold {
+				cb.open(now)
+			}
+		case outcomeSuccess:
+			cb.failures = 0
+		}
+	case CircuitHalfOpen:
+		// Requests admitted before the circuit opened say nothing about recovery
+		if !trial {
+			return
+		}
+		switch o {
+		case outcomeFailure:
+			cb.open(now)
+		case outcomeSuccess:
+			cb.successes++
+			if cb.successes >= cb.policy.SuccessThreshold {
+				cb.state = CircuitClosed
+				cb.failures = 0
+			}
+		}
+	}
+}
+
+// open trips the circuit. Callers must hold the lock.
+func (cb *circuitBreaker) open(now time.Time) {
+	cb.state = CircuitOpen
+	cb.openedAt = now
+	cb.failures = 0
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerPolicy{
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 2,
+		SuccessThreshold: 2,
+	})
+	now := time.Now()
+
+	request := func(o outcome) {
+		t.Helper()
+		ok, trial := cb.allow(now)
+		if !ok {
+			t.Fatalf("request refused in state %v", cb.get(now))
+		}
+		cb.done(trial, o, now)
+	}
+
+	// Failures must be consecutive to open the circuit
+	request(outcomeFailure)
+	request(outcomeFailure)
+	request(outcomeSuccess)
+	request(outcomeFailure)
+	request(outcomeFailure)
+	if got := cb.get(now); got != CircuitClosed {
+		t.Fatalf("state = %v after non-consecutive failures, want closed", got)
+	}
+
+	request(outcomeFailure)
+	if got := cb.get(now); got != CircuitOpen {
+		t.Fatalf("state = %v after 3 consecutive failures, want open", got)
+	}
+	if ok, _ := cb.allow(now); ok || !cb.refusing(now) {
+		t.Fatal("open circuit admitted a request")
+	}
+
+	// After the timeout a limited number of trials are let through
+	now = now.Add(10 * time.Second)
+	if got := cb.get(now); got != CircuitHalfOpen {
+		t.Fatalf("state = %v after the open timeout, want half-open", got)
+	}
+	ok1, trial1 := cb.allow(now)
+	ok2, trial2 := cb.allow(now)
+	if !ok1 || !ok2 || !trial1 || !trial2 {
+		t.Fatal("half-open circuit refused its trial requests")
+	}
+	if ok, _ := cb.allow(now); ok || !cb.refusing(now) {
+		t.Fatal("half-open circuit admitted more trials than allowed")
+	}
+
+	// A failed trial opens the circuit again
+	cb.done(trial1, outcomeSuccess, now)
+	cb.done(trial2, outcomeFailure, now)
+	if got := cb.get(now); got != CircuitOpen {
+		t.Fatalf("state = %v after a failed trial, want open", got)
+	}
+
+	// Enough successful trials close it
+	now = now.Add(10 * time.Second)
+	request(outcomeSuccess)
+	if got := cb.get(now); got != CircuitHalfOpen {
+		t.Fatalf("state = %v after one successful trial, want half-open", got)
+	}
+	request(outcomeSuccess)
+	if got := cb.get(now); got != CircuitClosed {
+		t.Fatalf("state = %v after 2 successful trials, want closed", got)
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerPolicy{})
+	if cb != nil {
+		t.Fatal("breaker created without a failure threshold")
+	}
+
+	now := time.Now()
+	cb.done(false, outcomeFailure, now)
+	if ok, _ := cb.allow(now); !ok || cb.refusing(now) || cb.get(now) != CircuitClosed {
+		t.Error("disabled breaker refused a request")
+	}
+}
+
+func TestBackend_CircuitBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	b := NewBackend(serverURL, httputil.NewSingleHostReverseProxy(serverURL),
+		WithCircuitBreaker(CircuitBreakerPolicy{FailureThreshold: 2, OpenTimeout: time.Minute}))
+
+	for i := 0; i < 2; i++ {
+		b.Serve(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+
+	if got := b.GetCircuitState(); got != CircuitOpen {
+		t.Fatalf("Backend.GetCircuitState() = %v, want open", got)
+	}
+	if !b.IsCircuitOpen() {
+		t.Error("Backend.IsCircuitOpen() = false, want true")
+	}
+
+	// Requests are refused without reaching the upstream
+	recorder := httptest.NewRecorder()
+	b.Serve(recorder, httptest.NewRequest("GET", "/", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Backend.Serve() status code = %v, want %v", recorder.Code, http.StatusServiceUnavailable)
+	}
+	if got := b.GetOutcomes().Requests; got != 2 {
+		t.Errorf("%d requests recorded, want 2", got)
+	}
+}
@@ -0,0 +1,203 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// grpcHealthCheckPath is the method of the standard gRPC health service
+const grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+// grpc.health.v1.HealthCheckResponse.ServingStatus values
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// GRPCHealthChecker speaks the gRPC health checking protocol
+// (grpc.health.v1.Health/Check) and considers a backend healthy when it
+// reports SERVING. An empty Service asks about the server as a whole.
+// Backends are reached over h2c unless TLS is set or their URL uses https.
+//
+// The protocol is small enough to implement on top of net/http's HTTP/2
+// support, which keeps a gRPC dependency out of the load balancer.
+type GRPCHealthChecker struct {
+	Service   string
+	TLS       bool
+	TLSConfig *tls.Config
+	Timeout   time.Duration
+
+	once   sync.Once
+	client *http.Client
+}
+
+func (c *GRPCHealthChecker) Check(ctx context.Context, u *url.URL) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	scheme := "http"
+	if c.TLS || u.Scheme == "https" {
+		scheme = "https"
+	}
+	target := url.URL{Scheme: scheme, Host: u.Host, Path: grpcHealthCheckPath}
+
+	body := grpcFrame(encodeHealthCheckRequest(c.Service))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckBody))
+	if err != nil {
+		return fmt.Errorf("failed to read health check response: %w", err)
+	}
+
+	// Errors may be sent as trailers-only responses, with the status in the headers
+	trailer := resp.Trailer
+	if trailer.Get("Grpc-Status") == "" {
+		trailer = resp.Header
+	}
+	if status := trailer.Get("Grpc-Status"); status != "0" {
+		return fmt.Errorf("grpc status %q: %s", status, trailer.Get("Grpc-Message"))
+	}
+
+	msg, err := parseGRPCFrame(payload)
+	if err != nil {
+		return err
+	}
+
+	status, err := decodeHealthCheckStatus(msg)
+	if err != nil {
+		return err
+	}
+	if status != 1 {
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = strconv.FormatUint(status, 10)
+		}
+		return fmt.Errorf("service is %s", name)
+	}
+	return nil
+}
+
+// httpClient lazily builds an HTTP/2 only client: h2c with prior knowledge
+// for http URLs and ALPN negotiated h2 for https
+func (c *GRPCHealthChecker) httpClient() *http.Client {
+	c.once.Do(func() {
+		var protocols http.Protocols
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+
+		c.client = &http.Client{
+			Transport: &http.Transport{
+				Protocols:       &protocols,
+				TLSClientConfig: c.TLSConfig,
+			},
+		}
+	})
+	return c.client
+}
+
+// grpcFrame wraps msg in the length-prefixed, uncompressed gRPC message framing
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	return append(frame, msg...)
+}
+
+func parseGRPCFrame(frame []byte) ([]byte, error) {
+	if len(frame) < 5 {
+		return nil, errors.New("short gRPC frame")
+	}
+	if frame[0] != 0 {
+		return nil, errors.New("compressed gRPC responses are not supported")
+	}
+
+	n := binary.BigEndian.Uint32(frame[1:5])
+	if uint32(len(frame)-5) < n {
+		return nil, errors.New("truncated gRPC frame")
+	}
+	return frame[5 : 5+n], nil
+}
+
+// encodeHealthCheckRequest encodes HealthCheckRequest{service = 1}
+func encodeHealthCheckRequest(service string) []byte {
+	if service == "" {
+		return nil
+	}
+
+	msg := []byte{0x0a}
+	msg = binary.AppendUvarint(msg, uint64(len(service)))
+	return append(msg, service...)
+}
+
+// decodeHealthCheckStatus extracts field 1 (status) of a HealthCheckResponse,
+// skipping any fields added by newer versions of the protocol
+func decodeHealthCheckStatus(msg []byte) (uint64, error) {
+	var status uint64
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return 0, errors.New("malformed health check response")
+		}
+		msg = msg[n:]
+
+		field, wireType := key>>3, key&7
+		switch wireType {
+		case 0:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return 0, errors.New("malformed health check response")
+			}
+			msg = msg[n:]
+			if field == 1 {
+				status = v
+			}
+		case 1, 5:
+			size := 8
+			if wireType == 5 {
+				size = 4
+			}
+			if len(msg) < size {
+				return 0, errors.New("malformed health check response")
+			}
+			msg = msg[size:]
+		case 2:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < l {
+				return 0, errors.New("malformed health check response")
+			}
+			msg = msg[n+int(l):]
+		default:
+			return 0, fmt.Errorf("unsupported wire type %d in health check response", wireType)
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newGRPCHealthServer serves grpc.health.v1.Health/Check, answering with the
+// status registered for the requested service
+func newGRPCHealthServer(statuses map[string]uint64) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != grpcHealthCheckPath || r.Header.Get("Content-Type") != "application/grpc" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		msg, err := parseGRPCFrame(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// HealthCheckRequest has a single string field
+		var service string
+		if len(msg) > 2 {
+			service = string(msg[2:])
+		}
+
+		status, ok := statuses[service]
+		w.Header().Set("Content-Type", "application/grpc")
+		if !ok {
+			// Trailers-only NOT_FOUND, as sent by grpc-go for unknown services
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "unknown service")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write(grpcFrame([]byte{0x08, byte(status)}))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server.Config.Protocols = &protocols
+	return server
+}
+
+func TestGRPCHealthChecker(t *testing.T) {
+	statuses := map[string]uint64{
+		"":           1,
+		"orders":     1,
+		"payments":   2,
+		"inventory":  0,
+		"deprecated": 3,
+	}
+
+	h2c := newGRPCHealthServer(statuses)
+	h2c.Start()
+	defer h2c.Close()
+
+	tlsServer := newGRPCHealthServer(statuses)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	h2cURL, _ := url.Parse(h2c.URL)
+	tlsURL, _ := url.Parse(tlsServer.URL)
+	tlsConfig := tlsServer.Client().Transport.(*http.Transport).TLSClientConfig
+
+	tests := []struct {
+		name    string
+		checker *GRPCHealthChecker
+		u       *url.URL
+		wantErr bool
+	}{
+		{
+			name:    "overall server health",
+			checker: &GRPCHealthChecker{},
+			u:       h2cURL,
+		},
+		{
+			name:    "serving service",
+			checker: &GRPCHealthChecker{Service: "orders"},
+			u:       h2cURL,
+		},
+		{
+			name:    "not serving service",
+			checker: &GRPCHealthChecker{Service: "payments"},
+			u:       h2cURL,
+			wantErr: true,
+		},
+		{
+			name:    "unknown status",
+			checker: &GRPCHealthChecker{Service: "inventory"},
+			u:       h2cURL,
+			wantErr: true,
+		},
+		{
+			name:    "unregistered service",
+			checker: &GRPCHealthChecker{Service: "billing"},
+			u:       h2cURL,
+			wantErr: true,
+		},
+		{
+			name:    "tls",
+			checker: &GRPCHealthChecker{Service: "orders", TLS: true, TLSConfig: tlsConfig},
+			u:       &url.URL{Scheme: "http", Host: tlsURL.Host},
+		},
+		{
+			name:    "tls inferred from https scheme",
+			checker: &GRPCHealthChecker{TLSConfig: tlsConfig},
+			u:       tlsURL,
+		},
+		{
+			name:    "untrusted certificate",
+			checker: &GRPCHealthChecker{TLS: true},
+			u:       tlsURL,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.checker.Check(context.Background(), tt.u)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCHealthChecker.Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeHealthCheckStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     []byte
+		want    uint64
+		wantErr bool
+	}{
+		{name: "empty message", msg: nil, want: 0},
+		{name: "serving", msg: []byte{0x08, 0x01}, want: 1},
+		{name: "unknown fields are skipped", msg: []byte{0x12, 0x02, 'h', 'i', 0x08, 0x02, 0x1d, 0, 0, 0, 0}, want: 2},
+		{name: "truncated varint", msg: []byte{0x08}, wantErr: true},
+		{name: "truncated bytes", msg: []byte{0x12, 0x05, 'h'}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeHealthCheckStatus(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeHealthCheckStatus() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeHealthCheckStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
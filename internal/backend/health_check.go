@@ -0,0 +1,194 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxHealthCheckBody caps how much of a health check response is read for
+// body matching
+const maxHealthCheckBody = 64 << 10
+
+// HealthChecker probes a backend and returns an error when it should not
+// receive traffic
+type HealthChecker interface {
+	Check(ctx context.Context, u *url.URL) error
+}
+
+// CheckerTimeout returns the timeout checker applies to each probe, or 0 when
+// it sets none
+func CheckerTimeout(checker HealthChecker) time.Duration {
+	switch c := checker.(type) {
+	case *TCPHealthChecker:
+		return c.Timeout
+	case *HTTPHealthChecker:
+		return c.Timeout
+	case *GRPCHealthChecker:
+		return c.Timeout
+	}
+	return 0
+}
+
+// TCPHealthChecker considers a backend healthy when it accepts a TCP connection
+type TCPHealthChecker struct {
+	Timeout time.Duration
+}
+
+func (c *TCPHealthChecker) Check(ctx context.Context, u *url.URL) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+	Min, Max int
+}
+
+// ParseStatusRange accepts "200", "200-299" or "2xx"
+func ParseStatusRange(s string) (StatusRange, error) {
+	s = strings.TrimSpace(s)
+
+	if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") {
+		class, err := strconv.Atoi(s[:1])
+		if err != nil || class < 1 || class > 5 {
+			return StatusRange{}, fmt.Errorf("invalid status class %q", s)
+		}
+		return StatusRange{Min: class * 100, Max: class*100 + 99}, nil
+	}
+
+	first, last, isRange := strings.Cut(s, "-")
+	low, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return StatusRange{}, fmt.Errorf("invalid status %q", s)
+	}
+
+	high := low
+	if isRange {
+		if high, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return StatusRange{}, fmt.Errorf("invalid status %q", s)
+		}
+	}
+
+	if low < 100 || high > 599 || low > high {
+		return StatusRange{}, fmt.Errorf("invalid status range %q", s)
+	}
+	return StatusRange{Min: low, Max: high}, nil
+}
+
+func (r StatusRange) contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+// HTTPHealthChecker sends a request to the backend and checks the status code
+// and, optionally, the response body
+type HTTPHealthChecker struct {
+	Path           string
+	Method         string
+	Headers        map[string]string
+	Timeout        time.Duration
+	ExpectedStatus []StatusRange
+	BodyContains   string
+	BodyRegex      *regexp.Regexp
+	Client         *http.Client
+}
+
+// defaultExpectedStatus accepts success and redirect responses
+var defaultExpectedStatus = []StatusRange{{Min: 200, Max: 399}}
+
+// noRedirectClient reports redirects as they are instead of following them
+// away from the backend
+var noRedirectClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func (c *HTTPHealthChecker) Check(ctx context.Context, u *url.URL) error {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	target, err := url.Parse(c.Path)
+	if err != nil {
+		return fmt.Errorf("invalid health check path: %w", err)
+	}
+
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.ResolveReference(target).String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = noRedirectClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	expected := c.ExpectedStatus
+	if len(expected) == 0 {
+		expected = defaultExpectedStatus
+	}
+
+	statusOK := false
+	for _, r := range expected {
+		statusOK = statusOK || r.contains(resp.StatusCode)
+	}
+	if !statusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if c.BodyContains == "" && c.BodyRegex == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthCheckBody))
+	if err != nil {
+		return fmt.Errorf("failed to read health check body: %w", err)
+	}
+
+	if c.BodyContains != "" && !strings.Contains(string(body), c.BodyContains) {
+		return errors.New("response body does not contain expected text")
+	}
+	if c.BodyRegex != nil && !c.BodyRegex.Match(body) {
+		return errors.New("response body does not match expected pattern")
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseStatusRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    StatusRange
+		wantErr bool
+	}{
+		{in: "200", want: StatusRange{Min: 200, Max: 200}},
+		{in: "200-299", want: StatusRange{Min: 200, Max: 299}},
+		{in: "3xx", want: StatusRange{Min: 300, Max: 399}},
+		{in: "299-200", wantErr: true},
+		{in: "9xx", wantErr: true},
+		{in: "700", wantErr: true},
+		{in: "ok", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseStatusRange(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatusRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStatusRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPHealthChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			if r.Header.Get("X-Probe") != "eisodos" || r.URL.Query().Get("full") != "1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"status":"ok","version":"1.2.3"}`))
+		case "/head":
+			if r.Method != http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, r, "https://example.com/", http.StatusFound)
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+
+	tests := []struct {
+		name    string
+		checker *HTTPHealthChecker
+		wantErr bool
+	}{
+		{
+			name: "matching status, headers and body",
+			checker: &HTTPHealthChecker{
+				Path:         "/healthz?full=1",
+				Headers:      map[string]string{"X-Probe": "eisodos"},
+				BodyContains: `"status":"ok"`,
+				BodyRegex:    regexp.MustCompile(`"version":"\d+\.\d+\.\d+"`),
+			},
+		},
+		{
+			name:    "server error",
+			checker: &HTTPHealthChecker{Path: "/broken"},
+			wantErr: true,
+		},
+		{
+			name:    "server error explicitly accepted",
+			checker: &HTTPHealthChecker{Path: "/broken", ExpectedStatus: []StatusRange{{Min: 500, Max: 599}}},
+		},
+		{
+			name: "body mismatch",
+			checker: &HTTPHealthChecker{
+				Path:         "/healthz?full=1",
+				Headers:      map[string]string{"X-Probe": "eisodos"},
+				BodyContains: "degraded",
+			},
+			wantErr: true,
+		},
+		{
+			name: "regex mismatch",
+			checker: &HTTPHealthChecker{
+				Path:      "/healthz?full=1",
+				Headers:   map[string]string{"X-Probe": "eisodos"},
+				BodyRegex: regexp.MustCompile(`"version":"2\.`),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "custom method",
+			checker: &HTTPHealthChecker{Path: "/head", Method: http.MethodHead},
+		},
+		{
+			name:    "redirects are not followed",
+			checker: &HTTPHealthChecker{Path: "/moved", ExpectedStatus: []StatusRange{{Min: 302, Max: 302}}},
+		},
+		{
+			name:    "timeout",
+			checker: &HTTPHealthChecker{Path: "/slow", Timeout: 50 * time.Millisecond},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.checker.Check(context.Background(), u)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTCPHealthChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	u, _ := url.Parse(server.URL)
+
+	checker := &TCPHealthChecker{Timeout: time.Second}
+	if err := checker.Check(context.Background(), u); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+
+	server.Close()
+	if err := checker.Check(context.Background(), u); err == nil {
+		t.Error("Check() error = nil for a closed server, want an error")
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker HealthChecker
+		want    time.Duration
+	}{
+		{"tcp", &TCPHealthChecker{Timeout: time.Second}, time.Second},
+		{"http", &HTTPHealthChecker{Timeout: 2 * time.Second}, 2 * time.Second},
+		{"grpc", &GRPCHealthChecker{Timeout: 3 * time.Second}, 3 * time.Second},
+		{"unset", &HTTPHealthChecker{}, 0},
+		{"nil", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckerTimeout(tt.checker); got != tt.want {
+				t.Errorf("CheckerTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
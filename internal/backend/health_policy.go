@@ -0,0 +1,20 @@
+package backend
+
+import "time"
+
+// HealthPolicy controls how often a backend is probed and how many
+// consecutive probe results it takes to change its state. Zero fields fall
+// back to the health monitor's defaults.
+type HealthPolicy struct {
+	// Interval between probes of a healthy backend
+	Interval time.Duration
+	// UnhealthyInterval between probes of a backend that is down or failing
+	UnhealthyInterval time.Duration
+	// Jitter randomises each interval by up to this fraction, e.g. 0.1 for
+	// ±10%. Nil keeps the monitor's default and 0 disables jitter
+	Jitter *float64
+	// HealthyThreshold consecutive successes bring a down backend back up
+	HealthyThreshold int
+	// UnhealthyThreshold consecutive failures take a healthy backend down
+	UnhealthyThreshold int
+}
@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// defaultLatencyDecay is the time constant of the latency average: a sample
+// loses about two thirds of its influence after this long
+const defaultLatencyDecay = 10 * time.Second
+
+// peakEWMA is an exponentially weighted moving average of response latency
+// that jumps straight to any sample above it. Slow responses are reflected
+// immediately while recovery is smoothed over the decay window. Like
+// Finagle's, the average also decays while no samples arrive, so a backend
+// that was once slow is tried again instead of starving.
+type peakEWMA struct {
+	mu    sync.Mutex
+	value float64
+	stamp time.Time
+	decay time.Duration
+}
+
+func (e *peakEWMA) observe(rtt time.Duration, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	sample := float64(rtt)
+	switch {
+	case e.stamp.IsZero(), sample > e.value:
+		e.value = sample
+	default:
+		w := math.Exp(-float64(now.Sub(e.stamp)) / float64(e.decay))
+		e.value = e.value*w + sample*(1-w)
+	}
+	e.stamp = now
+}
+
+// get returns the average as of now, decayed by the time since the last
+// sample
+func (e *peakEWMA) get(now time.Time) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if elapsed := now.Sub(e.stamp); !e.stamp.IsZero() && elapsed > 0 {
+		return time.Duration(e.value * math.Exp(-float64(elapsed)/float64(e.decay)))
+	}
+	return time.Duration(e.value)
+}
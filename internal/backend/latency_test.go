@@ -0,0 +1,49 @@
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeakEWMA(t *testing.T) {
+	now := time.Now()
+	e := peakEWMA{decay: 10 * time.Second}
+
+	if got := e.get(now); got != 0 {
+		t.Errorf("get() = %v before any sample, want 0", got)
+	}
+
+	// The first sample is taken as is
+	e.observe(100*time.Millisecond, now)
+	if got := e.get(now); got != 100*time.Millisecond {
+		t.Errorf("get() = %v, want 100ms", got)
+	}
+
+	// Peaks are adopted immediately
+	e.observe(time.Second, now.Add(time.Millisecond))
+	if got := e.get(now.Add(time.Millisecond)); got != time.Second {
+		t.Errorf("get() = %v after a peak, want 1s", got)
+	}
+
+	// Faster samples pull the average down gradually, more so the longer
+	// since the previous sample
+	e.observe(100*time.Millisecond, now.Add(2*time.Millisecond))
+	if got := e.get(now.Add(2 * time.Millisecond)); got < 999*time.Millisecond {
+		t.Errorf("get() = %v right after a peak, want close to 1s", got)
+	}
+
+	e.observe(100*time.Millisecond, now.Add(time.Minute))
+	if got := e.get(now.Add(time.Minute)); got > 110*time.Millisecond {
+		t.Errorf("get() = %v after a minute of fast responses, want close to 100ms", got)
+	}
+
+	// Without samples the average keeps decaying, so a backend that was
+	// slow is not avoided forever
+	e.observe(time.Second, now.Add(2*time.Minute))
+	if got := e.get(now.Add(2*time.Minute + 10*time.Second)); got < 360*time.Millisecond || got > 380*time.Millisecond {
+		t.Errorf("get() = %v one decay window after a 1s peak, want about 368ms", got)
+	}
+	if got := e.get(now.Add(5 * time.Minute)); got > time.Millisecond {
+		t.Errorf("get() = %v minutes after the last sample, want close to 0", got)
+	}
+}
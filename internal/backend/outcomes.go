@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// Outcomes counts the results of requests proxied to a backend. Requests and
+// Failures only ever grow; ConsecutiveFailures resets on every success.
+type Outcomes struct {
+	Requests            uint64
+	Failures            uint64
+	ConsecutiveFailures uint64
+}
+
+// outcomeCounters records passive health data from live traffic
+type outcomeCounters struct {
+	requests            atomic.Uint64
+	failures            atomic.Uint64
+	consecutiveFailures atomic.Uint64
+}
+
+// outcome classifies a proxied request
+type outcome int
+
+const (
+	// outcomeIgnored says nothing about the backend
+	outcomeIgnored outcome = iota
+	outcomeSuccess
+	outcomeFailure
+)
+
+func (c *outcomeCounters) record(o outcome) {
+	switch o {
+	case outcomeSuccess:
+		c.requests.Add(1)
+		c.consecutiveFailures.Store(0)
+	case outcomeFailure:
+		c.requests.Add(1)
+		c.failures.Add(1)
+		c.consecutiveFailures.Add(1)
+	}
+}
+
+func (c *outcomeCounters) get() Outcomes {
+	return Outcomes{
+		Requests:            c.requests.Load(),
+		Failures:            c.failures.Load(),
+		ConsecutiveFailures: c.consecutiveFailures.Load(),
+	}
+}
+
+// classify judges a proxied request by its status. Connect errors and
+// timeouts reach the client as 5xx from the proxy's error handler, so the
+// status covers them too. Requests abandoned by the client are ignored.
+func classify(req *http.Request, status int) outcome {
+	if errors.Is(req.Context().Err(), context.Canceled) {
+		return outcomeIgnored
+	}
+	if status >= http.StatusInternalServerError {
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+// ProxyErrorObserver is implemented by response writers that want to know
+// why a backend failed to produce a response. ObserveProxyError is called
+// before the proxy's error handler writes its response.
+type ProxyErrorObserver interface {
+	ObserveProxyError(error)
+}
+
+// statusRecorder captures the status code written through it and passes
+// proxy errors on to the wrapped writer
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) observeProxyError(err error) {
+	if o, ok := r.ResponseWriter.(ProxyErrorObserver); ok {
+		o.ObserveProxyError(err)
+	}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// flushing and connection upgrades
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
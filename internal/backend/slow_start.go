@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SlowStartCurve is how the weight of a warming backend grows
+type SlowStartCurve int
+
+const (
+	// SlowStartLinear grows the weight by the same amount every instant
+	SlowStartLinear SlowStartCurve = iota
+	// SlowStartExponential grows the weight by the same factor every
+	// instant, keeping it low for longer
+	SlowStartExponential
+)
+
+func (c SlowStartCurve) String() string {
+	switch c {
+	case SlowStartLinear:
+		return "linear"
+	case SlowStartExponential:
+		return "exponential"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSlowStartCurve parses linear (the default when empty) or exponential
+func ParseSlowStartCurve(s string) (SlowStartCurve, error) {
+	switch s {
+	case "", "linear":
+		return SlowStartLinear, nil
+	case "exponential":
+		return SlowStartExponential, nil
+	default:
+		return 0, fmt.Errorf("unknown slow start curve: %s", s)
+	}
+}
+
+// SlowStartPolicy ramps up the traffic of a backend that has just recovered
+// or been added, giving it time to warm its caches. A zero Window disables
+// slow start.
+type SlowStartPolicy struct {
+	// Window over which the weight ramps up to full
+	Window time.Duration
+	// Curve of the ramp. Defaults to linear.
+	Curve SlowStartCurve
+	// MinFactor of the full weight the ramp starts from. Defaults to 0.1.
+	MinFactor float64
+}
+
+const defaultSlowStartMinFactor = 0.1
+
+// slowStart tracks the ramp of one backend. A nil slowStart is always at
+// full weight.
+type slowStart struct {
+	policy SlowStartPolicy
+	// since holds when the current ramp started, in Unix nanoseconds, or zero
+	since atomic.Int64
+}
+
+func newSlowStart(policy SlowStartPolicy) *slowStart {
+	if policy.Window <= 0 {
+		return nil
+	}
+	if policy.MinFactor <= 0 || policy.MinFactor > 1 {
+		policy.MinFactor = defaultSlowStartMinFactor
+	}
+	return &slowStart{policy: policy}
+}
+
+// start begins a new ramp at now
+func (s *slowStart) start(now time.Time) {
+	if s == nil {
+		return
+	}
+	s.since.Store(now.UnixNano())
+}
+
+// factor returns the fraction of its weight the backend gets at now
+func (s *slowStart) factor(now time.Time) float64 {
+	if s == nil {
+		return 1
+	}
+
+	since := s.since.Load()
+	if since == 0 {
+		return 1
+	}
+
+	progress := float64(now.UnixNano()-since) / float64(s.policy.Window)
+	if progress >= 1 {
+		return 1
+	}
+	progress = max(progress, 0)
+
+	lo := s.policy.MinFactor
+	if s.policy.Curve == SlowStartExponential {
+		return lo * math.Pow(1/lo, progress)
+	}
+	return lo + (1-lo)*progress
+}
@@ -0,0 +1,76 @@
+package backend
+
+import (
+	"math"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSlowStart_Factor(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name    string
+		policy  SlowStartPolicy
+		elapsed time.Duration
+		want    float64
+	}{
+		{"linear start", SlowStartPolicy{Window: 10 * time.Second}, 0, 0.1},
+		{"linear halfway", SlowStartPolicy{Window: 10 * time.Second}, 5 * time.Second, 0.55},
+		{"linear done", SlowStartPolicy{Window: 10 * time.Second}, 10 * time.Second, 1},
+		{"exponential start", SlowStartPolicy{Window: 10 * time.Second, Curve: SlowStartExponential, MinFactor: 0.01}, 0, 0.01},
+		{"exponential halfway", SlowStartPolicy{Window: 10 * time.Second, Curve: SlowStartExponential, MinFactor: 0.01}, 5 * time.Second, 0.1},
+		{"exponential done", SlowStartPolicy{Window: 10 * time.Second, Curve: SlowStartExponential, MinFactor: 0.01}, time.Minute, 1},
+		{"custom min factor", SlowStartPolicy{Window: 10 * time.Second, MinFactor: 0.5}, 5 * time.Second, 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSlowStart(tt.policy)
+			s.start(start)
+			if got := s.factor(start.Add(tt.elapsed)); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("factor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlowStart_Disabled(t *testing.T) {
+	s := newSlowStart(SlowStartPolicy{})
+	if s != nil {
+		t.Fatalf("newSlowStart() = %v without a window, want nil", s)
+	}
+
+	s.start(time.Now())
+	if got := s.factor(time.Now()); got != 1 {
+		t.Errorf("factor() = %v, want 1", got)
+	}
+}
+
+func TestBackend_SlowStartOnRecovery(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080")
+	b := NewBackend(u, nil, WithSlowStart(SlowStartPolicy{Window: time.Hour}))
+
+	if got := b.GetSlowStartFactor(); got != 1 {
+		t.Errorf("GetSlowStartFactor() = %v for a new backend, want 1", got)
+	}
+
+	// Staying up does not restart the ramp
+	b.SetAlive(true)
+	if got := b.GetSlowStartFactor(); got != 1 {
+		t.Errorf("GetSlowStartFactor() = %v after a repeated SetAlive(true), want 1", got)
+	}
+
+	b.SetAlive(false)
+	b.SetAlive(true)
+	if got := b.GetSlowStartFactor(); got >= 0.2 {
+		t.Errorf("GetSlowStartFactor() = %v after recovering, want about 0.1", got)
+	}
+
+	b = NewBackend(u, nil, WithSlowStart(SlowStartPolicy{Window: time.Hour}))
+	b.StartSlowStart()
+	if got := b.GetSlowStartFactor(); got >= 0.2 {
+		t.Errorf("GetSlowStartFactor() = %v after StartSlowStart, want about 0.1", got)
+	}
+}
@@ -0,0 +1,223 @@
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Variable is a value of the request being proxied that header templates
+// can refer to as {name}
+type Variable int
+
+const (
+	// ClientIP is the address of the client, {client_ip}
+	ClientIP Variable = iota + 1
+	// RequestID is the X-Request-Id of the request, {request_id}
+	RequestID
+	// BackendURL is the URL of the backend serving the request,
+	// {backend_url}
+	BackendURL
+	// Host is the host requested by the client, {host}
+	Host
+	// Method is the request method, {method}
+	Method
+	// Path is the request path, {path}
+	Path
+)
+
+var variableNames = map[string]Variable{
+	"client_ip":   ClientIP,
+	"request_id":  RequestID,
+	"backend_url": BackendURL,
+	"host":        Host,
+	"method":      Method,
+	"path":        Path,
+}
+
+// Vars holds the values of the variables for one request
+type Vars struct {
+	ClientIP   string
+	RequestID  string
+	BackendURL string
+	Host       string
+	Method     string
+	Path       string
+}
+
+func (v *Vars) value(variable Variable) string {
+	switch variable {
+	case ClientIP:
+		return v.ClientIP
+	case RequestID:
+		return v.RequestID
+	case BackendURL:
+		return v.BackendURL
+	case Host:
+		return v.Host
+	case Method:
+		return v.Method
+	case Path:
+		return v.Path
+	default:
+		return ""
+	}
+}
+
+// template is a header value in which {name} is replaced by a variable
+type template struct {
+	literals  []string
+	variables []Variable
+}
+
+// parseTemplate splits s into the literals around its variables, so that
+// literals has one element more than variables
+func parseTemplate(s string) (template, error) {
+	var t template
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			t.literals = append(t.literals, s)
+			return t, nil
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return template{}, fmt.Errorf("unterminated variable in %q", s)
+		}
+
+		name := s[start+1 : start+end]
+		variable, ok := variableNames[name]
+		if !ok {
+			return template{}, fmt.Errorf("unknown variable {%s}", name)
+		}
+
+		t.literals = append(t.literals, s[:start])
+		t.variables = append(t.variables, variable)
+		s = s[start+end+1:]
+	}
+}
+
+func (t *template) expand(vars *Vars) string {
+	if len(t.variables) == 0 {
+		return t.literals[0]
+	}
+
+	var b strings.Builder
+	for i, variable := range t.variables {
+		b.WriteString(t.literals[i])
+		b.WriteString(vars.value(variable))
+	}
+	b.WriteString(t.literals[len(t.literals)-1])
+	return b.String()
+}
+
+// Header is a header name and a value template
+type Header struct {
+	Name  string
+	Value string
+}
+
+type opKind int
+
+const (
+	opRemove opKind = iota
+	opSet
+	opAdd
+)
+
+type op struct {
+	kind  opKind
+	name  string
+	value template
+}
+
+// Rules rewrite the headers of a request or a response. A nil *Rules leaves
+// headers untouched.
+type Rules struct {
+	ops []op
+}
+
+// NewRules returns rules removing the headers named in remove, then setting
+// the headers of set, replacing their values, and finally adding those of
+// add to any values already present. Without any rule it returns nil.
+func NewRules(remove []string, set, add []Header) (*Rules, error) {
+	r := &Rules{}
+	for i, name := range remove {
+		if name == "" {
+			return nil, fmt.Errorf("remove %d: name is required", i)
+		}
+		r.ops = append(r.ops, op{kind: opRemove, name: http.CanonicalHeaderKey(name)})
+	}
+
+	for _, group := range []struct {
+		kind    opKind
+		label   string
+		headers []Header
+	}{{opSet, "set", set}, {opAdd, "add", add}} {
+		for i, h := range group.headers {
+			if h.Name == "" {
+				return nil, fmt.Errorf("%s %d: name is required", group.label, i)
+			}
+			value, err := parseTemplate(h.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %d: %w", group.label, i, err)
+			}
+			r.ops = append(r.ops, op{kind: group.kind, name: http.CanonicalHeaderKey(h.Name), value: value})
+		}
+	}
+
+	if len(r.ops) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// Join returns rules applying each of rules in turn, skipping nil ones
+func Join(rules ...*Rules) *Rules {
+	var joined *Rules
+	for _, r := range rules {
+		switch {
+		case r == nil:
+		case joined == nil:
+			joined = r
+		default:
+			joined = &Rules{ops: append(append([]op(nil), joined.ops...), r.ops...)}
+		}
+	}
+	return joined
+}
+
+// Apply rewrites h, expanding templates with vars
+func (r *Rules) Apply(h http.Header, vars *Vars) {
+	if r == nil {
+		return
+	}
+
+	for i := range r.ops {
+		o := &r.ops[i]
+		switch o.kind {
+		case opRemove:
+			delete(h, o.name)
+		case opSet:
+			h[o.name] = []string{o.value.expand(vars)}
+		case opAdd:
+			h[o.name] = append(h[o.name], o.value.expand(vars))
+		}
+	}
+}
+
+// Uses reports whether any template of the rules refers to variable
+func (r *Rules) Uses(variable Variable) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, o := range r.ops {
+		if slices.Contains(o.value.variables, variable) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package headers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRules_Apply(t *testing.T) {
+	vars := &Vars{
+		ClientIP:   "203.0.113.7",
+		RequestID:  "abc123",
+		BackendURL: "http://10.0.0.1:8080",
+		Host:       "example.com",
+		Method:     "GET",
+		Path:       "/orders",
+	}
+
+	tests := []struct {
+		name   string
+		remove []string
+		set    []Header
+		add    []Header
+		header http.Header
+		want   http.Header
+	}{
+		{
+			name:   "remove",
+			remove: []string{"cookie", "X-Missing"},
+			header: http.Header{"Cookie": {"a=b"}, "Accept": {"*/*"}},
+			want:   http.Header{"Accept": {"*/*"}},
+		},
+		{
+			name:   "set replaces values",
+			set:    []Header{{Name: "x-env", Value: "prod"}},
+			header: http.Header{"X-Env": {"dev", "test"}},
+			want:   http.Header{"X-Env": {"prod"}},
+		},
+		{
+			name:   "add appends values",
+			add:    []Header{{Name: "Via", Value: "eisodos"}},
+			header: http.Header{"Via": {"1.1 cdn"}},
+			want:   http.Header{"Via": {"1.1 cdn", "eisodos"}},
+		},
+		{
+			name: "templates",
+			set: []Header{
+				{Name: "X-Real-IP", Value: "{client_ip}"},
+				{Name: "X-Request-Id", Value: "{request_id}"},
+				{Name: "X-Upstream", Value: "{method} {backend_url}{path} for {host}"},
+			},
+			header: http.Header{},
+			want: http.Header{
+				"X-Real-Ip":    {"203.0.113.7"},
+				"X-Request-Id": {"abc123"},
+				"X-Upstream":   {"GET http://10.0.0.1:8080/orders for example.com"},
+			},
+		},
+		{
+			name:   "remove comes before set and add",
+			remove: []string{"X-Trace"},
+			set:    []Header{{Name: "X-Trace", Value: "set"}},
+			add:    []Header{{Name: "X-Trace", Value: "added"}},
+			header: http.Header{"X-Trace": {"client"}},
+			want:   http.Header{"X-Trace": {"set", "added"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := NewRules(tt.remove, tt.set, tt.add)
+			if err != nil {
+				t.Fatalf("NewRules() error = %v", err)
+			}
+
+			rules.Apply(tt.header, vars)
+			if !reflect.DeepEqual(tt.header, tt.want) {
+				t.Errorf("Apply() = %v, want %v", tt.header, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRules_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		remove  []string
+		set     []Header
+		add     []Header
+		wantErr string
+	}{
+		{"unnamed remove", []string{""}, nil, nil, "remove 0: name is required"},
+		{"unnamed set", nil, []Header{{Value: "x"}}, nil, "set 0: name is required"},
+		{"unknown variable", nil, nil, []Header{{Name: "X", Value: "{user}"}}, "add 0: unknown variable {user}"},
+		{"unterminated variable", nil, []Header{{Name: "X", Value: "a {host"}}, nil, "set 0: unterminated variable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRules(tt.remove, tt.set, tt.add)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewRules() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	pool, _ := NewRules(nil, []Header{{Name: "X-Pool", Value: "api"}, {Name: "X-Env", Value: "pool"}}, nil)
+	route, _ := NewRules(nil, []Header{{Name: "X-Env", Value: "{request_id}"}}, nil)
+
+	if got := Join(nil, nil); got != nil {
+		t.Errorf("Join(nil, nil) = %v, want nil", got)
+	}
+	if got := Join(nil, pool); got != pool {
+		t.Errorf("Join(nil, pool) = %v, want pool", got)
+	}
+
+	joined := Join(pool, nil, route)
+	header := http.Header{}
+	joined.Apply(header, &Vars{RequestID: "route"})
+
+	// Later rules win
+	want := http.Header{"X-Pool": {"api"}, "X-Env": {"route"}}
+	if !reflect.DeepEqual(header, want) {
+		t.Errorf("Apply() = %v, want %v", header, want)
+	}
+
+	if !joined.Uses(RequestID) || pool.Uses(RequestID) {
+		t.Error("Uses(RequestID) does not follow the templates")
+	}
+}
+
+func TestNewRules_Empty(t *testing.T) {
+	rules, err := NewRules(nil, nil, nil)
+	if err != nil || rules != nil {
+		t.Errorf("NewRules() = %v, %v, want nil rules", rules, err)
+	}
+
+	// Nil rules leave headers alone
+	header := http.Header{"Accept": {"*/*"}}
+	rules.Apply(header, &Vars{})
+	if len(header) != 1 {
+		t.Errorf("Apply() on nil rules changed the header: %v", header)
+	}
+}
@@ -0,0 +1,458 @@
+package router
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PathType is how a route compares its path with the request path
+type PathType int
+
+const (
+	// PathPrefix matches the path and everything below it, element by
+	// element, so /api matches /api and /api/users but not /apis
+	PathPrefix PathType = iota
+	// PathExact matches the path only
+	PathExact
+	// PathRegex matches paths for which the whole path matches the regular
+	// expression
+	PathRegex
+)
+
+func (t PathType) String() string {
+	switch t {
+	case PathPrefix:
+		return "prefix"
+	case PathExact:
+		return "exact"
+	case PathRegex:
+		return "regex"
+	default:
+		return "unknown"
+	}
+}
+
+// ParsePathType parses prefix (the default when empty), exact or regex
+func ParsePathType(s string) (PathType, error) {
+	switch s {
+	case "", "prefix":
+		return PathPrefix, nil
+	case "exact":
+		return PathExact, nil
+	case "regex":
+		return PathRegex, nil
+	default:
+		return 0, fmt.Errorf("unknown path type: %s", s)
+	}
+}
+
+// MatchType is how a ValueMatcher compares a request value
+type MatchType int
+
+const (
+	// MatchExact matches values equal to the expected one
+	MatchExact MatchType = iota
+	// MatchPrefix matches values starting with the expected one
+	MatchPrefix
+	// MatchRegex matches values wholly matching the regular expression
+	MatchRegex
+	// MatchPresent matches whenever the value is set, whatever it is
+	MatchPresent
+)
+
+func (t MatchType) String() string {
+	switch t {
+	case MatchExact:
+		return "exact"
+	case MatchPrefix:
+		return "prefix"
+	case MatchRegex:
+		return "regex"
+	case MatchPresent:
+		return "present"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMatchType parses exact (the default when empty), prefix, regex or
+// present
+func ParseMatchType(s string) (MatchType, error) {
+	switch s {
+	case "", "exact":
+		return MatchExact, nil
+	case "prefix":
+		return MatchPrefix, nil
+	case "regex":
+		return MatchRegex, nil
+	case "present":
+		return MatchPresent, nil
+	default:
+		return 0, fmt.Errorf("unknown match type: %s", s)
+	}
+}
+
+// ValueMatcher matches a named request value such as a header, query
+// parameter or cookie
+type ValueMatcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+
+	re *regexp.Regexp
+}
+
+func (m *ValueMatcher) compile() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch m.Type {
+	case MatchExact, MatchPrefix, MatchPresent:
+	case MatchRegex:
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		m.re = re
+	default:
+		return fmt.Errorf("unknown match type: %v", m.Type)
+	}
+	return nil
+}
+
+// matches reports whether any of values, the values set under the name of
+// the matcher, satisfies it
+func (m *ValueMatcher) matches(values []string) bool {
+	for _, v := range values {
+		switch m.Type {
+		case MatchPresent:
+			return true
+		case MatchPrefix:
+			if strings.HasPrefix(v, m.Value) {
+				return true
+			}
+		case MatchRegex:
+			if m.re.MatchString(v) {
+				return true
+			}
+		default:
+			if v == m.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Mirror sends a copy of Percent of the requests of a route to Pool
+type Mirror struct {
+	Pool    string
+	Percent float64
+}
+
+func (m *Mirror) validate() error {
+	if m.Pool == "" {
+		return fmt.Errorf("mirror pool is required")
+	}
+	if m.Percent <= 0 || m.Percent > 100 {
+		return fmt.Errorf("mirror percent must be in (0, 100]: %v", m.Percent)
+	}
+	return nil
+}
+
+// Route sends matching requests to a pool, or splits them among several.
+// Empty fields match every request.
+type Route struct {
+	// Name identifies the route when adjusting its split at runtime
+	Name string
+	// Host is an exact host name or a wildcard such as *.example.com, which
+	// matches any subdomain but not example.com itself
+	Host     string
+	Path     string
+	PathType PathType
+	// Methods the route accepts, any of them matching
+	Methods []string
+	// Headers, Query and Cookies must all match. Header names are case
+	// insensitive; query parameter and cookie names are not.
+	Headers []ValueMatcher
+	Query   []ValueMatcher
+	Cookies []ValueMatcher
+	// Pool receives the requests matching the route
+	Pool string
+	// Split divides the requests matching the route among pools by weight,
+	// in place of Pool
+	Split []WeightedPool
+	// PinBy keeps clients on the same side of the split
+	PinBy PinKey
+	// Mirror optionally copies requests to a shadow pool
+	Mirror *Mirror
+
+	pathRegex *regexp.Regexp
+	split     *split
+}
+
+// compile validates the route and prepares its matchers
+func (r *Route) compile() error {
+	switch {
+	case r.Pool == "" && len(r.Split) == 0:
+		return fmt.Errorf("pool or split is required")
+	case r.Pool != "" && len(r.Split) > 0:
+		return fmt.Errorf("pool and split are exclusive")
+	case len(r.Split) > 0:
+		s, err := newSplit(r.Split, r.PinBy)
+		if err != nil {
+			return err
+		}
+		r.Split = s.weights.Load().pools
+		r.split = s
+	}
+
+	if r.Mirror != nil {
+		if err := r.Mirror.validate(); err != nil {
+			return err
+		}
+		mirror := *r.Mirror
+		r.Mirror = &mirror
+	}
+
+	r.Host = strings.ToLower(r.Host)
+	if strings.Contains(strings.TrimPrefix(r.Host, "*."), "*") {
+		return fmt.Errorf("invalid host %q: only a leading *. wildcard is supported", r.Host)
+	}
+
+	r.Methods = slices.Clone(r.Methods)
+	for i, method := range r.Methods {
+		r.Methods[i] = strings.ToUpper(method)
+	}
+
+	switch r.PathType {
+	case PathPrefix, PathExact:
+		if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("invalid path %q: must start with /", r.Path)
+		}
+	case PathRegex:
+		re, err := regexp.Compile("^(?:" + r.Path + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid path regex: %w", err)
+		}
+		r.pathRegex = re
+	default:
+		return fmt.Errorf("unknown path type: %v", r.PathType)
+	}
+
+	var err error
+	if r.Headers, err = compileMatchers("header", r.Headers); err != nil {
+		return err
+	}
+	if r.Query, err = compileMatchers("query parameter", r.Query); err != nil {
+		return err
+	}
+	r.Cookies, err = compileMatchers("cookie", r.Cookies)
+	return err
+}
+
+// compileMatchers returns compiled copies of matchers, naming what they
+// match in errors
+func compileMatchers(kind string, matchers []ValueMatcher) ([]ValueMatcher, error) {
+	compiled := slices.Clone(matchers)
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s %d: %w", kind, i, err)
+		}
+	}
+	return compiled, nil
+}
+
+// PickPool returns the pool receiving req: Pool, or for a split route one of
+// the split pools chosen by weight
+func (r *Route) PickPool(req *http.Request) string {
+	if r.split == nil {
+		return r.Pool
+	}
+	return r.split.pick(req)
+}
+
+// PickMirror returns the pool receiving a copy of the request, or "" if
+// the request is not mirrored
+func (r *Route) PickMirror() string {
+	if r.Mirror == nil || rand.Float64()*100 >= r.Mirror.Percent {
+		return ""
+	}
+	return r.Mirror.Pool
+}
+
+// Pools returns every pool the route can send requests to, its mirror
+// included
+func (r *Route) Pools() []string {
+	var pools []string
+	if len(r.Split) == 0 {
+		pools = append(pools, r.Pool)
+	}
+	for _, p := range r.Split {
+		pools = append(pools, p.Pool)
+	}
+	if r.Mirror != nil {
+		pools = append(pools, r.Mirror.Pool)
+	}
+	return pools
+}
+
+// Weights returns the current split of the route, or nil if it has none
+func (r *Route) Weights() []WeightedPool {
+	if r.split == nil {
+		return nil
+	}
+	return slices.Clone(r.split.weights.Load().pools)
+}
+
+// Matches reports whether req satisfies every condition of the route
+func (r *Route) Matches(req *http.Request) bool {
+	return r.matchesHost(req) && r.matchesPath(req) && r.matchesMethod(req) &&
+		r.matchesHeaders(req) && r.matchesQuery(req) && r.matchesCookies(req)
+}
+
+func (r *Route) matchesHost(req *http.Request) bool {
+	if r.Host == "" {
+		return true
+	}
+
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if suffix, ok := strings.CutPrefix(r.Host, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == r.Host
+}
+
+func (r *Route) matchesPath(req *http.Request) bool {
+	if r.Path == "" {
+		return true
+	}
+
+	path := req.URL.Path
+	switch r.PathType {
+	case PathExact:
+		return path == r.Path
+	case PathRegex:
+		return r.pathRegex.MatchString(path)
+	default:
+		prefix := strings.TrimSuffix(r.Path, "/")
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+}
+
+func (r *Route) matchesMethod(req *http.Request) bool {
+	return len(r.Methods) == 0 || slices.Contains(r.Methods, req.Method)
+}
+
+func (r *Route) matchesHeaders(req *http.Request) bool {
+	for i := range r.Headers {
+		if !r.Headers[i].matches(req.Header.Values(r.Headers[i].Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) matchesQuery(req *http.Request) bool {
+	if len(r.Query) == 0 {
+		return true
+	}
+
+	query := req.URL.Query()
+	for i := range r.Query {
+		if !r.Query[i].matches(query[r.Query[i].Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) matchesCookies(req *http.Request) bool {
+	for i := range r.Cookies {
+		cookies := req.CookiesNamed(r.Cookies[i].Name)
+		values := make([]string, len(cookies))
+		for j, c := range cookies {
+			values[j] = c.Value
+		}
+		if !r.Cookies[i].matches(values) {
+			return false
+		}
+	}
+	return true
+}
+
+// Table is an ordered list of routes in which the first match wins
+type Table struct {
+	routes []*Route
+}
+
+// NewTable validates routes and builds a table trying them in order
+func NewTable(routes []Route) (*Table, error) {
+	t := &Table{routes: make([]*Route, len(routes))}
+	names := make(map[string]bool)
+	for i := range routes {
+		route := routes[i]
+		if err := route.compile(); err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		if route.Name != "" {
+			if names[route.Name] {
+				return nil, fmt.Errorf("route %d: duplicate name %q", i, route.Name)
+			}
+			names[route.Name] = true
+		}
+		t.routes[i] = &route
+	}
+	return t, nil
+}
+
+// SetWeights replaces the split weights of the named route. Pools of the
+// split missing from weights stop receiving requests.
+func (t *Table) SetWeights(name string, weights map[string]int) error {
+	for _, route := range t.routes {
+		if route.Name != name {
+			continue
+		}
+		if route.split == nil {
+			return fmt.Errorf("route %q has no split", name)
+		}
+
+		pools := make([]WeightedPool, len(route.Split))
+		for i, p := range route.Split {
+			pools[i] = WeightedPool{Pool: p.Pool, Weight: weights[p.Pool]}
+		}
+		for pool := range weights {
+			if !slices.ContainsFunc(pools, func(p WeightedPool) bool { return p.Pool == pool }) {
+				return fmt.Errorf("route %q does not split to pool %q", name, pool)
+			}
+		}
+		return route.split.set(pools)
+	}
+	return fmt.Errorf("unknown route %q", name)
+}
+
+// Match returns the first route matching r, or nil if there is none
+func (t *Table) Match(r *http.Request) *Route {
+	for _, route := range t.routes {
+		if route.Matches(r) {
+			return route
+		}
+	}
+	return nil
+}
+
+// Routes returns the routes of the table in order
+func (t *Table) Routes() []*Route {
+	return t.routes
+}
@@ -0,0 +1,280 @@
+package router
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRoute_Matches(t *testing.T) {
+	tests := []struct {
+		name   string
+		route  Route
+		method string
+		target string
+		want   bool
+	}{
+		{"empty route matches everything", Route{}, "GET", "http://any.host/any/path", true},
+
+		{"exact host", Route{Host: "example.com"}, "GET", "http://example.com/", true},
+		{"exact host ignores case and port", Route{Host: "Example.com"}, "GET", "http://EXAMPLE.com:8080/", true},
+		{"exact host mismatch", Route{Host: "example.com"}, "GET", "http://api.example.com/", false},
+		{"wildcard host subdomain", Route{Host: "*.example.com"}, "GET", "http://api.example.com/", true},
+		{"wildcard host nested subdomain", Route{Host: "*.example.com"}, "GET", "http://v1.api.example.com/", true},
+		{"wildcard host apex", Route{Host: "*.example.com"}, "GET", "http://example.com/", false},
+		{"wildcard host suffix only", Route{Host: "*.example.com"}, "GET", "http://badexample.com/", false},
+
+		{"prefix equal", Route{Path: "/api"}, "GET", "http://h/api", true},
+		{"prefix below", Route{Path: "/api"}, "GET", "http://h/api/users", true},
+		{"prefix with trailing slash", Route{Path: "/api/"}, "GET", "http://h/api/users", true},
+		{"prefix by element", Route{Path: "/api"}, "GET", "http://h/apis", false},
+		{"root prefix", Route{Path: "/"}, "GET", "http://h/anything", true},
+		{"exact path", Route{Path: "/health", PathType: PathExact}, "GET", "http://h/health", true},
+		{"exact path below", Route{Path: "/health", PathType: PathExact}, "GET", "http://h/health/live", false},
+		{"regex path", Route{Path: `/users/\d+`, PathType: PathRegex}, "GET", "http://h/users/42", true},
+		{"regex path is anchored", Route{Path: `/users/\d+`, PathType: PathRegex}, "GET", "http://h/users/42/posts", false},
+
+		{"method", Route{Methods: []string{"get", "HEAD"}}, "GET", "http://h/", true},
+		{"method mismatch", Route{Methods: []string{"GET"}}, "POST", "http://h/", false},
+
+		{"all conditions", Route{Host: "*.example.com", Path: "/api", Methods: []string{"POST"}}, "POST", "http://api.example.com/api/x", true},
+		{"one condition fails", Route{Host: "*.example.com", Path: "/api", Methods: []string{"POST"}}, "POST", "http://api.example.com/web", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.route.Pool = "pool"
+			if err := tt.route.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if got := tt.route.Matches(req); got != tt.want {
+				t.Errorf("Matches(%s %s) = %v, want %v", tt.method, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTable_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr string
+	}{
+		{"missing pool", Route{}, "pool or split is required"},
+		{"pool and split", Route{Pool: "p", Split: []WeightedPool{{Pool: "q", Weight: 1}}}, "pool and split are exclusive"},
+		{"zero split", Route{Split: []WeightedPool{{Pool: "p"}, {Pool: "q"}}}, "must not all be zero"},
+		{"negative split weight", Route{Split: []WeightedPool{{Pool: "p", Weight: -1}, {Pool: "q", Weight: 2}}}, "cannot be negative"},
+		{"split pool twice", Route{Split: []WeightedPool{{Pool: "p", Weight: 1}, {Pool: "p", Weight: 1}}}, "listed twice"},
+		{"split pinned twice", Route{Split: []WeightedPool{{Pool: "p", Weight: 1}}, PinBy: PinKey{Header: "h", Cookie: "c"}}, "not both"},
+		{"inner wildcard", Route{Host: "api.*.com", Pool: "p"}, "only a leading *. wildcard"},
+		{"bare wildcard", Route{Host: "*", Pool: "p"}, "only a leading *. wildcard"},
+		{"relative path", Route{Path: "api", Pool: "p"}, "must start with /"},
+		{"invalid regex", Route{Path: "(", PathType: PathRegex, Pool: "p"}, "invalid path regex"},
+		{"unnamed header", Route{Headers: []ValueMatcher{{Value: "x"}}, Pool: "p"}, "header 0: name is required"},
+		{"invalid query regex", Route{Query: []ValueMatcher{{Name: "q", Type: MatchRegex, Value: "("}}, Pool: "p"}, "query parameter 0: invalid regex"},
+		{"mirror without pool", Route{Pool: "p", Mirror: &Mirror{Percent: 10}}, "mirror pool is required"},
+		{"mirror of nothing", Route{Pool: "p", Mirror: &Mirror{Pool: "shadow"}}, "mirror percent must be in (0, 100]"},
+		{"mirror of more than everything", Route{Pool: "p", Mirror: &Mirror{Pool: "shadow", Percent: 150}}, "mirror percent must be in (0, 100]"},
+		{"unknown cookie match type", Route{Cookies: []ValueMatcher{{Name: "c", Type: MatchType(9)}}, Pool: "p"}, "cookie 0: unknown match type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTable([]Route{tt.route})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewTable() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTable_Match(t *testing.T) {
+	methods := []string{"GET"}
+	table, err := NewTable([]Route{
+		{Host: "api.example.com", Path: "/v1", Pool: "api-v1"},
+		{Host: "api.example.com", Pool: "api"},
+		{Path: "/static", Methods: methods, Pool: "static"},
+	})
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+
+	// The table does not share the caller's slices
+	methods[0] = "POST"
+
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{"GET", "http://api.example.com/v1/users", "api-v1"},
+		{"GET", "http://api.example.com/v2/users", "api"},
+		{"GET", "http://www.example.com/static/app.js", "static"},
+		{"POST", "http://www.example.com/static/app.js", ""},
+		{"GET", "http://www.example.com/", ""},
+	}
+
+	for _, tt := range tests {
+		route := table.Match(httptest.NewRequest(tt.method, tt.target, nil))
+
+		got := ""
+		if route != nil {
+			got = route.Pool
+		}
+		if got != tt.want {
+			t.Errorf("Match(%s %s) = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestParsePathType(t *testing.T) {
+	for s, want := range map[string]PathType{"": PathPrefix, "prefix": PathPrefix, "exact": PathExact, "regex": PathRegex} {
+		got, err := ParsePathType(s)
+		if err != nil || got != want {
+			t.Errorf("ParsePathType(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+
+	if _, err := ParsePathType("glob"); err == nil {
+		t.Error("ParsePathType(glob) succeeded, want error")
+	}
+}
+
+func TestRoute_MatchesValues(t *testing.T) {
+	tenant := func(typ MatchType, value string) Route {
+		return Route{Headers: []ValueMatcher{{Name: "X-Tenant", Type: typ, Value: value}}}
+	}
+	beta := func(typ MatchType, value string) Route {
+		return Route{Query: []ValueMatcher{{Name: "beta", Type: typ, Value: value}}}
+	}
+	canary := func(typ MatchType, value string) Route {
+		return Route{Cookies: []ValueMatcher{{Name: "canary", Type: typ, Value: value}}}
+	}
+
+	tests := []struct {
+		name    string
+		route   Route
+		target  string
+		headers map[string][]string
+		want    bool
+	}{
+		{"header exact", tenant(MatchExact, "acme"), "/", map[string][]string{"x-tenant": {"acme"}}, true},
+		{"header exact mismatch", tenant(MatchExact, "acme"), "/", map[string][]string{"X-Tenant": {"acme-eu"}}, false},
+		{"header any value", tenant(MatchExact, "acme"), "/", map[string][]string{"X-Tenant": {"other", "acme"}}, true},
+		{"header prefix", tenant(MatchPrefix, "acme"), "/", map[string][]string{"X-Tenant": {"acme-eu"}}, true},
+		{"header regex", tenant(MatchRegex, "acme-(eu|us)"), "/", map[string][]string{"X-Tenant": {"acme-us"}}, true},
+		{"header regex is anchored", tenant(MatchRegex, "acme"), "/", map[string][]string{"X-Tenant": {"acme-us"}}, false},
+		{"header present", tenant(MatchPresent, ""), "/", map[string][]string{"X-Tenant": {""}}, true},
+		{"header absent", tenant(MatchPresent, ""), "/", nil, false},
+		{"header exact empty needs presence", tenant(MatchExact, ""), "/", nil, false},
+
+		{"query exact", beta(MatchExact, "1"), "/?beta=1", nil, true},
+		{"query exact mismatch", beta(MatchExact, "1"), "/?beta=0", nil, false},
+		{"query present without value", beta(MatchPresent, ""), "/?beta", nil, true},
+		{"query absent", beta(MatchPresent, ""), "/?alpha=1", nil, false},
+		{"query prefix", beta(MatchPrefix, "on"), "/?beta=only", nil, true},
+
+		{"cookie exact", canary(MatchExact, "yes"), "/", map[string][]string{"Cookie": {"a=b; canary=yes"}}, true},
+		{"cookie mismatch", canary(MatchExact, "yes"), "/", map[string][]string{"Cookie": {"canary=no"}}, false},
+		{"cookie present", canary(MatchPresent, ""), "/", map[string][]string{"Cookie": {"canary="}}, true},
+		{"cookie absent", canary(MatchPresent, ""), "/", map[string][]string{"Cookie": {"other=1"}}, false},
+
+		{
+			"all matchers",
+			Route{
+				Headers: []ValueMatcher{{Name: "X-Tenant", Value: "acme"}},
+				Query:   []ValueMatcher{{Name: "beta", Value: "1"}},
+			},
+			"/?beta=1",
+			map[string][]string{"X-Tenant": {"acme"}},
+			true,
+		},
+		{
+			"one matcher fails",
+			Route{
+				Headers: []ValueMatcher{{Name: "X-Tenant", Value: "acme"}},
+				Query:   []ValueMatcher{{Name: "beta", Value: "1"}},
+			},
+			"/",
+			map[string][]string{"X-Tenant": {"acme"}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.route.Pool = "pool"
+			if err := tt.route.compile(); err != nil {
+				t.Fatalf("compile() error = %v", err)
+			}
+
+			req := httptest.NewRequest("GET", tt.target, nil)
+			for name, values := range tt.headers {
+				for _, v := range values {
+					req.Header.Add(name, v)
+				}
+			}
+			if got := tt.route.Matches(req); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMatchType(t *testing.T) {
+	for s, want := range map[string]MatchType{"": MatchExact, "exact": MatchExact, "prefix": MatchPrefix, "regex": MatchRegex, "present": MatchPresent} {
+		got, err := ParseMatchType(s)
+		if err != nil || got != want {
+			t.Errorf("ParseMatchType(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+
+	if _, err := ParseMatchType("suffix"); err == nil {
+		t.Error("ParseMatchType(suffix) succeeded, want error")
+	}
+}
+
+func TestRoute_PickMirror(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  *Mirror
+		wantMin int
+		wantMax int
+	}{
+		{"no mirror", nil, 0, 0},
+		{"all requests", &Mirror{Pool: "shadow", Percent: 100}, 1000, 1000},
+		{"some requests", &Mirror{Pool: "shadow", Percent: 10}, 50, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewTable([]Route{{Pool: "p", Mirror: tt.mirror}})
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+			route := table.Routes()[0]
+
+			mirrored := 0
+			for i := 0; i < 1000; i++ {
+				switch pool := route.PickMirror(); pool {
+				case "":
+				case "shadow":
+					mirrored++
+				default:
+					t.Fatalf("PickMirror() = %q, want shadow", pool)
+				}
+			}
+			if mirrored < tt.wantMin || mirrored > tt.wantMax {
+				t.Errorf("mirrored %d of 1000 requests, want [%d, %d]", mirrored, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+
+	route := Route{Pool: "p", Mirror: &Mirror{Pool: "shadow", Percent: 100}}
+	if got, want := route.Pools(), []string{"p", "shadow"}; !slices.Equal(got, want) {
+		t.Errorf("Pools() = %v, want %v", got, want)
+	}
+}
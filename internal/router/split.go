@@ -0,0 +1,109 @@
+package router
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync/atomic"
+
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+)
+
+// WeightedPool is one side of a traffic split
+type WeightedPool struct {
+	Pool   string
+	Weight int
+}
+
+// PinKey names the request header or cookie whose value pins a client to
+// one side of a split. Requests without the value are split at random.
+type PinKey struct {
+	Header string
+	Cookie string
+}
+
+// extract returns the pinning value of r, or "" if there is none
+func (k PinKey) extract(r *http.Request) string {
+	switch {
+	case k.Header != "":
+		return r.Header.Get(k.Header)
+	case k.Cookie != "":
+		if c, err := r.Cookie(k.Cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// split divides the traffic of a route among pools. Its weights can be
+// replaced while requests are being served.
+type split struct {
+	pin     PinKey
+	weights atomic.Pointer[splitWeights]
+}
+
+type splitWeights struct {
+	pools []WeightedPool
+	total int
+}
+
+func newSplit(pools []WeightedPool, pin PinKey) (*split, error) {
+	if pin.Header != "" && pin.Cookie != "" {
+		return nil, fmt.Errorf("split can be pinned by a header or a cookie, not both")
+	}
+
+	s := &split{pin: pin}
+	if err := s.set(slices.Clone(pools)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// set validates and installs new weights
+func (s *split) set(pools []WeightedPool) error {
+	w := &splitWeights{pools: pools}
+	for i, p := range pools {
+		if p.Pool == "" {
+			return fmt.Errorf("split pool is required")
+		}
+		if slices.ContainsFunc(pools[:i], func(o WeightedPool) bool { return o.Pool == p.Pool }) {
+			return fmt.Errorf("split pool %s is listed twice", p.Pool)
+		}
+		if p.Weight < 0 {
+			return fmt.Errorf("split weight of %s cannot be negative: %d", p.Pool, p.Weight)
+		}
+		w.total += p.Weight
+	}
+
+	if w.total == 0 {
+		return fmt.Errorf("split weights must not all be zero")
+	}
+
+	s.weights.Store(w)
+	return nil
+}
+
+// pick returns the pool for r. Pinned requests map to a fixed point of the
+// weight range, so raising the weight of a later pool only moves clients into
+// it, never out of it.
+func (s *split) pick(r *http.Request) string {
+	w := s.weights.Load()
+
+	var point float64
+	if key := s.pin.extract(r); key != "" {
+		point = float64(serverpool.HashString(key)>>11) / (1 << 53)
+	} else {
+		point = rand.Float64()
+	}
+
+	target := point * float64(w.total)
+	cumulative := 0
+	for _, p := range w.pools {
+		cumulative += p.Weight
+		if target < float64(cumulative) {
+			return p.Pool
+		}
+	}
+	return w.pools[len(w.pools)-1].Pool
+}
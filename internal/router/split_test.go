@@ -0,0 +1,166 @@
+package router
+
+import (
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newSplitTable(t *testing.T, pin PinKey) *Table {
+	t.Helper()
+
+	table, err := NewTable([]Route{{
+		Name:  "canary",
+		Split: []WeightedPool{{Pool: "stable", Weight: 95}, {Pool: "canary", Weight: 5}},
+		PinBy: pin,
+	}})
+	if err != nil {
+		t.Fatalf("NewTable() error = %v", err)
+	}
+	return table
+}
+
+func TestRoute_PickPoolDistribution(t *testing.T) {
+	table := newSplitTable(t, PinKey{Header: "X-User"})
+	route := table.Routes()[0]
+
+	const requests = 20000
+	tests := []struct {
+		name   string
+		header bool
+	}{
+		{"random", false},
+		{"pinned", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := map[string]int{}
+			for i := 0; i < requests; i++ {
+				req := httptest.NewRequest("GET", "/", nil)
+				if tt.header {
+					req.Header.Set("X-User", fmt.Sprintf("user-%d", i))
+				}
+				counts[route.PickPool(req)]++
+			}
+
+			share := float64(counts["canary"]) / requests
+			if math.Abs(share-0.05) > 0.01 {
+				t.Errorf("canary share = %.3f, want about 0.05 (%v)", share, counts)
+			}
+		})
+	}
+}
+
+func TestRoute_PickPoolPinning(t *testing.T) {
+	tests := []struct {
+		name string
+		pin  PinKey
+	}{
+		{"header", PinKey{Header: "X-User"}},
+		{"cookie", PinKey{Cookie: "uid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newSplitTable(t, tt.pin)
+			route := table.Routes()[0]
+
+			pick := func(user string) string {
+				req := httptest.NewRequest("GET", "/", nil)
+				if tt.pin.Header != "" {
+					req.Header.Set(tt.pin.Header, user)
+				} else {
+					req.Header.Set("Cookie", tt.pin.Cookie+"="+user)
+				}
+				return route.PickPool(req)
+			}
+
+			before := map[string]string{}
+			for i := 0; i < 1000; i++ {
+				user := fmt.Sprintf("user-%d", i)
+				before[user] = pick(user)
+				if again := pick(user); again != before[user] {
+					t.Fatalf("%s moved from %s to %s", user, before[user], again)
+				}
+			}
+
+			// Growing the canary only moves clients into it
+			if err := table.SetWeights("canary", map[string]int{"stable": 50, "canary": 50}); err != nil {
+				t.Fatalf("SetWeights() error = %v", err)
+			}
+			moved := 0
+			for user, pool := range before {
+				after := pick(user)
+				if pool == "canary" && after != "canary" {
+					t.Errorf("%s left the canary after it grew", user)
+				}
+				if pool != after {
+					moved++
+				}
+			}
+			if moved == 0 {
+				t.Error("no client moved to the canary after it grew")
+			}
+		})
+	}
+}
+
+func TestTable_SetWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		route   string
+		weights map[string]int
+		wantErr string
+	}{
+		{"unknown route", "missing", map[string]int{"stable": 1}, `unknown route "missing"`},
+		{"unknown pool", "canary", map[string]int{"stable": 1, "beta": 1}, `does not split to pool "beta"`},
+		{"all zero", "canary", map[string]int{"stable": 0}, "must not all be zero"},
+		{"negative", "canary", map[string]int{"stable": 2, "canary": -1}, "cannot be negative"},
+		{"route without split", "plain", map[string]int{"stable": 1}, `route "plain" has no split`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := NewTable([]Route{
+				{Name: "plain", Path: "/plain", Pool: "stable"},
+				{Name: "canary", Split: []WeightedPool{{Pool: "stable", Weight: 95}, {Pool: "canary", Weight: 5}}},
+			})
+			if err != nil {
+				t.Fatalf("NewTable() error = %v", err)
+			}
+
+			err = table.SetWeights(tt.route, tt.weights)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SetWeights() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unmentioned pools stop receiving requests", func(t *testing.T) {
+		table := newSplitTable(t, PinKey{})
+		if err := table.SetWeights("canary", map[string]int{"canary": 1}); err != nil {
+			t.Fatalf("SetWeights() error = %v", err)
+		}
+
+		route := table.Routes()[0]
+		for i := 0; i < 100; i++ {
+			if got := route.PickPool(httptest.NewRequest("GET", "/", nil)); got != "canary" {
+				t.Fatalf("PickPool() = %s, want canary", got)
+			}
+		}
+		want := []WeightedPool{{Pool: "stable", Weight: 0}, {Pool: "canary", Weight: 1}}
+		if got := route.Weights(); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Weights() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestNewTable_DuplicateName(t *testing.T) {
+	_, err := NewTable([]Route{{Name: "a", Pool: "p"}, {Name: "a", Pool: "q"}})
+	if err == nil || !strings.Contains(err.Error(), `duplicate name "a"`) {
+		t.Errorf("NewTable() error = %v, want duplicate name", err)
+	}
+}
@@ -0,0 +1,59 @@
+package serverpool
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// bchServerPool implements consistent hashing with bounded loads
+// (Mirrokni et al.): no backend may carry more than (1+ε) times the average
+// load, so a hot key spills over to the next ring position instead of
+// overloading its owner. Keys keep their affinity while loads are balanced.
+type bchServerPool struct {
+	*chServerPool
+	epsilon float64
+}
+
+func (s *bchServerPool) GetNextValidPeer() backend.Backend {
+	return s.lookupBounded(0)
+}
+
+func (s *bchServerPool) GetNextValidPeerForRequest(r *http.Request) backend.Backend {
+	return s.lookupBounded(HashString(s.key.Extract(r)))
+}
+
+// lookupBounded returns the first available backend clockwise from h that is
+// below the load limit
+func (s *bchServerPool) lookupBounded(h uint64) backend.Backend {
+	limit := s.loadLimit()
+
+	peer := s.lookup(h, func(b backend.Backend) bool {
+		return isAvailable(b) && b.GetActiveConnections() < limit
+	})
+	if peer == nil {
+		// Loads moved while walking the ring; prefer serving over rejecting
+		peer = s.lookup(h, isAvailable)
+	}
+	return peer
+}
+
+// loadLimit is ceil((1+ε) × average load), counting the request being placed
+func (s *bchServerPool) loadLimit() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	alive, total := 0, 0
+	for _, b := range s.backends {
+		if isHealthy(b) {
+			alive++
+			total += b.GetActiveConnections()
+		}
+	}
+
+	if alive == 0 {
+		return 0
+	}
+	return int(math.Ceil((1 + s.epsilon) * float64(total+1) / float64(alive)))
+}
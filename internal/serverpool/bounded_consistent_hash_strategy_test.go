@@ -0,0 +1,72 @@
+package serverpool
+
+import (
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBoundedConsistentHashServerPool(t *testing.T) {
+	// An epsilon of zero is honoured and balances the load perfectly
+	for _, epsilon := range []float64{0.25, 0} {
+		t.Run(fmt.Sprintf("epsilon %v", epsilon), func(t *testing.T) {
+			key := HashKey{Source: HashByHeader, Name: "X-User"}
+			pool, backends := newTestHashPool(t, BoundedConsistentHash, 4, WithHashKey(key), WithLoadEpsilon(epsilon))
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-User", "popular")
+
+			// With no load the key behaves like plain consistent hashing
+			owner := NextValidPeer(pool, req)
+			if owner == nil {
+				t.Fatal("GetNextValidPeerForRequest() = nil, want non-nil")
+			}
+			if got := NextValidPeer(pool, req); got != owner {
+				t.Errorf("GetNextValidPeerForRequest() = %v, want %v", got.GetURL(), owner.GetURL())
+			}
+
+			// A single hot key must spill over instead of piling onto its owner
+			const requests = 100
+			for i := 0; i < requests; i++ {
+				NextValidPeer(pool, req).Serve(nil, nil)
+			}
+
+			limit := int(math.Ceil((1 + epsilon) * requests / float64(len(backends))))
+			for _, b := range backends {
+				if got := b.GetActiveConnections(); got > limit {
+					t.Errorf("backend %v carries %d requests, want at most %d", b.GetURL(), got, limit)
+				}
+			}
+			if got := owner.GetActiveConnections(); got < limit-1 {
+				t.Errorf("owner carries %d requests, want it filled close to %d before spilling", got, limit)
+			}
+		})
+	}
+}
+
+func TestBoundedConsistentHashServerPool_KeepsAffinityUnderBalancedLoad(t *testing.T) {
+	key := HashKey{Source: HashByPath}
+	bounded, _ := newTestHashPool(t, BoundedConsistentHash, 4, WithHashKey(key))
+	plain, _ := newTestHashPool(t, ConsistentHash, 4, WithHashKey(key))
+
+	// Without load every key maps exactly like the unbounded ring
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/item/%d", i), nil)
+		got, want := NextValidPeer(bounded, req), NextValidPeer(plain, req)
+		if got.GetURL().String() != want.GetURL().String() {
+			t.Fatalf("key %d mapped to %v, want %v", i, got.GetURL(), want.GetURL())
+		}
+	}
+}
+
+func TestBoundedConsistentHashServerPool_NoAliveBackends(t *testing.T) {
+	pool, backends := newTestHashPool(t, BoundedConsistentHash, 2)
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
@@ -0,0 +1,147 @@
+package serverpool
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+const (
+	// HeaderXForwardedFor is the default header naming the client address
+	HeaderXForwardedFor = "X-Forwarded-For"
+	// HeaderForwarded is the RFC 7239 header naming the client address
+	HeaderForwarded = "Forwarded"
+)
+
+// TrustedProxies is the set of networks whose forwarding header is believed
+// when determining the client address of a request
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+	header   string
+}
+
+// ParseTrustedProxies parses CIDRs or bare addresses, the latter trusting a
+// single host. header names the forwarding header the proxies set, either
+// X-Forwarded-For, the default when empty, or Forwarded. Only that header is
+// read; the other one could have been written by the client.
+func ParseTrustedProxies(cidrs []string, header string) (*TrustedProxies, error) {
+	t := &TrustedProxies{header: http.CanonicalHeaderKey(header)}
+	switch t.header {
+	case "":
+		t.header = HeaderXForwardedFor
+	case HeaderXForwardedFor, HeaderForwarded:
+	default:
+		return nil, fmt.Errorf("unsupported client IP header %q, must be %s or %s", header, HeaderXForwardedFor, HeaderForwarded)
+	}
+
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			t.prefixes = append(t.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		t.prefixes = append(t.prefixes, prefix.Masked())
+	}
+	return t, nil
+}
+
+func (t *TrustedProxies) trusts(addr netip.Addr) bool {
+	if t == nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	for _, p := range t.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent r. The immediate peer
+// is used unless it is a trusted proxy, in which case the chain of the
+// configured forwarding header is walked from the right and the first
+// untrusted hop wins; hops further left could have been forged by the client.
+// The walk stops at a hop that is not an address, such as an unknown or
+// obfuscated Forwarded node, and the closest trusted proxy is returned.
+func (t *TrustedProxies) ClientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(peer)
+	if err != nil || !t.trusts(addr) {
+		return peer
+	}
+
+	var hops []string
+	if t.header == HeaderForwarded {
+		hops = forwardedFor(r.Header.Values(HeaderForwarded))
+	} else {
+		hops = xForwardedFor(r.Header.Values(HeaderXForwardedFor))
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		if !t.trusts(hop) {
+			return hop.String()
+		}
+		// Every hop so far is a trusted proxy; the leftmost is the closest
+		// to a client
+		client = hop.String()
+	}
+	return client
+}
+
+// forwardedFor extracts the for= addresses of RFC 7239 Forwarded headers
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				name, node, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(name, "for") {
+					continue
+				}
+				hops = append(hops, stripPort(strings.Trim(node, `"`)))
+			}
+		}
+	}
+	return hops
+}
+
+func xForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, stripPort(hop))
+			}
+		}
+	}
+	return hops
+}
+
+// stripPort removes an optional port and IPv6 brackets from a node
+func stripPort(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.Trim(node, "[]")
+}
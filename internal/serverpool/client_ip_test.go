@@ -0,0 +1,152 @@
+package serverpool
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   []string
+		header  string
+		wantErr bool
+	}{
+		{name: "no proxies"},
+		{name: "cidrs and addresses", cidrs: []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}},
+		{name: "Forwarded header", cidrs: []string{"10.0.0.0/8"}, header: "forwarded"},
+		{name: "invalid cidr", cidrs: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "invalid address", cidrs: []string{"proxy.internal"}, wantErr: true},
+		{name: "unsupported header", cidrs: []string{"10.0.0.0/8"}, header: "X-Real-IP", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTrustedProxies(tt.cidrs, tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseTrustedProxies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}, "")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+	forwarded, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}, HeaderForwarded)
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    *TrustedProxies
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			proxies:    proxies,
+			remoteAddr: "203.0.113.9:4711",
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "untrusted peer cannot spoof X-Forwarded-For",
+			proxies:    proxies,
+			remoteAddr: "203.0.113.9:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "203.0.113.9",
+		},
+		{
+			name:       "trusted peer with X-Forwarded-For",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "chain of trusted proxies is skipped from the right",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 192.168.1.1, 10.9.9.9"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "Forwarded header is ignored by default",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:4711",
+			headers: map[string]string{
+				"Forwarded":       "for=1.1.1.1",
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			want: "198.51.100.1",
+		},
+		{
+			name:       "configured Forwarded header",
+			proxies:    forwarded,
+			remoteAddr: "10.1.2.3:4711",
+			headers: map[string]string{
+				"Forwarded":       `for="[2001:db8:cafe::17]:4711";proto=https, for=10.2.2.2`,
+				"X-Forwarded-For": "198.51.100.1",
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "X-Forwarded-For is ignored when Forwarded is configured",
+			proxies:    forwarded,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "unknown node stops the walk",
+			proxies:    forwarded,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"Forwarded": "for=198.51.100.1, for=unknown, for=10.2.2.2"},
+			want:       "10.2.2.2",
+		},
+		{
+			name:       "obfuscated node stops the walk",
+			proxies:    forwarded,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"Forwarded": `for=198.51.100.1, for="_hidden:4711"`},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "malformed X-Forwarded-For hop stops the walk",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, not-an-ip"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "trusted peer without forwarding headers",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:4711",
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "no trusted proxies configured",
+			remoteAddr: "10.1.2.3:4711",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:       "10.1.2.3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			if got := tt.proxies.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
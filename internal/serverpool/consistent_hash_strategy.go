@@ -0,0 +1,59 @@
+package serverpool
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// chServerPool maps a request key onto a hash ring so the same key keeps
+// landing on the same backend. Adding a backend only takes over the keys
+// between its new points and their predecessors, roughly 1/N of the total.
+type chServerPool struct {
+	backends []backend.Backend
+	ring     *hashRing
+	key      HashKey
+	mux      sync.RWMutex
+}
+
+// GetNextValidPeer has no key to hash, so it walks the ring from its origin
+func (s *chServerPool) GetNextValidPeer() backend.Backend {
+	return s.lookup(0, isAvailable)
+}
+
+func (s *chServerPool) GetNextValidPeerForRequest(r *http.Request) backend.Backend {
+	return s.lookup(HashString(s.key.Extract(r)), isAvailable)
+}
+
+// lookup returns the first backend clockwise from h that accept allows
+func (s *chServerPool) lookup(h uint64, accept func(backend.Backend) bool) backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var peer backend.Backend
+	s.ring.walk(h, func(owner int) bool {
+		if b := s.backends[owner]; accept(b) {
+			peer = b
+			return true
+		}
+		return false
+	})
+	return peer
+}
+
+func (s *chServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.ring.add(len(s.backends), b.GetURL().String())
+	s.backends = append(s.backends, b)
+}
+
+func (s *chServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *chServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
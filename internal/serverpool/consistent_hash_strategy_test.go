@@ -0,0 +1,106 @@
+package serverpool
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func newTestHashPool(t *testing.T, strategy LBStrategy, n int, opts ...Option) (ServerPool, []*mockBackend) {
+	t.Helper()
+
+	pool, err := NewServerPool(strategy, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	backends := make([]*mockBackend, n)
+	for i := range backends {
+		backends[i] = newWeightedMockBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), 1)
+		pool.AddBackend(backends[i])
+	}
+	return pool, backends
+}
+
+func TestConsistentHashServerPool(t *testing.T) {
+	key := HashKey{Source: HashByHeader, Name: "X-User"}
+	pool, backends := newTestHashPool(t, ConsistentHash, 3, WithHashKey(key))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User", "alice")
+
+	// The same key keeps landing on the same backend
+	first := NextValidPeer(pool, req)
+	if first == nil {
+		t.Fatal("GetNextValidPeerForRequest() = nil, want non-nil")
+	}
+	for i := 0; i < 10; i++ {
+		if got := NextValidPeer(pool, req); got != first {
+			t.Fatalf("GetNextValidPeerForRequest() = %v, want %v", got.GetURL(), first.GetURL())
+		}
+	}
+
+	// An unhealthy owner is skipped by walking the ring, and the key
+	// returns once it recovers
+	first.SetAlive(false)
+	fallback := NextValidPeer(pool, req)
+	if fallback == nil || fallback == first {
+		t.Fatalf("GetNextValidPeerForRequest() = %v, want another alive backend", fallback)
+	}
+	first.SetAlive(true)
+	if got := NextValidPeer(pool, req); got != first {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want %v after recovery", got.GetURL(), first.GetURL())
+	}
+
+	// No alive backends
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+	if got := NextValidPeer(pool, req); got != nil {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want nil", got.GetURL())
+	}
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestConsistentHashServerPool_Empty(t *testing.T) {
+	pool, _ := newTestHashPool(t, ConsistentHash, 0)
+
+	if got := NextValidPeer(pool, httptest.NewRequest("GET", "/", nil)); got != nil {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want nil", got)
+	}
+}
+
+func TestConsistentHashServerPool_MinimalRemapping(t *testing.T) {
+	const keys = 10000
+	key := HashKey{Source: HashByPath}
+	pool, _ := newTestHashPool(t, ConsistentHash, 4, WithHashKey(key))
+
+	before := make([]backend.Backend, keys)
+	for i := range before {
+		before[i] = NextValidPeer(pool, httptest.NewRequest("GET", fmt.Sprintf("/item/%d", i), nil))
+	}
+
+	added := newWeightedMockBackend("http://10.0.0.5:8080", 1)
+	pool.AddBackend(added)
+
+	moved := 0
+	for i := range before {
+		got := NextValidPeer(pool, httptest.NewRequest("GET", fmt.Sprintf("/item/%d", i), nil))
+		if got == before[i] {
+			continue
+		}
+		moved++
+		if got != added {
+			t.Fatalf("key %d moved between existing backends", i)
+		}
+	}
+
+	// Ideally 1/5 of the keys move to the new backend
+	if ratio := float64(moved) / keys; ratio < 0.1 || ratio > 0.3 {
+		t.Errorf("remapped %.2f of keys, want about 0.20", ratio)
+	}
+}
@@ -0,0 +1,80 @@
+package serverpool
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+)
+
+// HashKeySource names the request attribute a hash based strategy keys on
+type HashKeySource string
+
+const (
+	HashByClientIP HashKeySource = "ip"
+	HashByHeader   HashKeySource = "header"
+	HashByCookie   HashKeySource = "cookie"
+	HashByPath     HashKeySource = "path"
+)
+
+// HashKey selects the request attribute used as the hashing key. Name is the
+// header or cookie name and is ignored for the other sources.
+type HashKey struct {
+	Source HashKeySource
+	Name   string
+
+	proxies *TrustedProxies
+}
+
+// ParseHashKey builds a HashKey from its configuration form. An empty source
+// defaults to the client IP.
+func ParseHashKey(source, name string) (HashKey, error) {
+	key := HashKey{Source: HashKeySource(source), Name: name}
+
+	switch key.Source {
+	case "":
+		key.Source = HashByClientIP
+	case HashByClientIP, HashByPath:
+	case HashByHeader, HashByCookie:
+		if name == "" {
+			return HashKey{}, fmt.Errorf("hash key %q requires a name", source)
+		}
+	default:
+		return HashKey{}, fmt.Errorf("unknown hash key %q", source)
+	}
+
+	return key, nil
+}
+
+// Extract returns the key for r. Requests missing the attribute yield an
+// empty key and therefore all map to the same backend.
+func (k HashKey) Extract(r *http.Request) string {
+	switch k.Source {
+	case HashByHeader:
+		return r.Header.Get(k.Name)
+	case HashByCookie:
+		if c, err := r.Cookie(k.Name); err == nil {
+			return c.Value
+		}
+		return ""
+	case HashByPath:
+		return r.URL.Path
+	default:
+		return k.proxies.ClientIP(r)
+	}
+}
+
+// HashString maps s onto the 64-bit ring. FNV-1a alone clusters similar
+// inputs such as "backend#1" and "backend#2", so its output is run through
+// the splitmix64 finalizer. Traffic splits hash their pin keys with it too.
+func HashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
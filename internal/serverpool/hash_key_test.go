@@ -0,0 +1,78 @@
+package serverpool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseHashKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		keyName string
+		want    HashKey
+		wantErr bool
+	}{
+		{
+			name: "empty source defaults to client IP",
+			want: HashKey{Source: HashByClientIP},
+		},
+		{
+			name:    "header with name",
+			source:  "header",
+			keyName: "X-User",
+			want:    HashKey{Source: HashByHeader, Name: "X-User"},
+		},
+		{
+			name:    "cookie without name",
+			source:  "cookie",
+			wantErr: true,
+		},
+		{
+			name:    "unknown source",
+			source:  "body",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHashKey(tt.source, tt.keyName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHashKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHashKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashKeyExtract(t *testing.T) {
+	req := httptest.NewRequest("GET", "/cart/42", nil)
+	req.RemoteAddr = "192.0.2.7:51234"
+	req.Header.Set("X-User", "alice")
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+
+	tests := []struct {
+		name string
+		key  HashKey
+		want string
+	}{
+		{name: "client IP", key: HashKey{Source: HashByClientIP}, want: "192.0.2.7"},
+		{name: "header", key: HashKey{Source: HashByHeader, Name: "X-User"}, want: "alice"},
+		{name: "missing header", key: HashKey{Source: HashByHeader, Name: "X-Other"}, want: ""},
+		{name: "cookie", key: HashKey{Source: HashByCookie, Name: "session"}, want: "abc"},
+		{name: "missing cookie", key: HashKey{Source: HashByCookie, Name: "other"}, want: ""},
+		{name: "path", key: HashKey{Source: HashByPath}, want: "/cart/42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Extract(req); got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
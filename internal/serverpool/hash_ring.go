@@ -0,0 +1,55 @@
+package serverpool
+
+import (
+	"sort"
+	"strconv"
+)
+
+// hashRing places every backend on a 64-bit ring at virtualNodes points.
+// A key is owned by the first point clockwise from its hash.
+type hashRing struct {
+	points       []ringPoint
+	virtualNodes int
+}
+
+type ringPoint struct {
+	hash  uint64
+	owner int
+}
+
+func newHashRing(virtualNodes int) *hashRing {
+	return &hashRing{virtualNodes: virtualNodes}
+}
+
+// add places the backend with the given index and identity on the ring
+func (r *hashRing) add(owner int, id string) {
+	for i := 0; i < r.virtualNodes; i++ {
+		r.points = append(r.points, ringPoint{
+			hash:  HashString(id + "#" + strconv.Itoa(i)),
+			owner: owner,
+		})
+	}
+
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+}
+
+// walk calls visit for ring points clockwise from h until visit returns true
+// or the whole ring has been visited
+func (r *hashRing) walk(h uint64, visit func(owner int) bool) {
+	n := len(r.points)
+	if n == 0 {
+		return
+	}
+
+	start := sort.Search(n, func(i int) bool {
+		return r.points[i].hash >= h
+	})
+
+	for i := 0; i < n; i++ {
+		if visit(r.points[(start+i)%n].owner) {
+			return
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package serverpool
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+const (
+	// probeTimeout caps a single probe whose checker sets no timeout of its own
+	probeTimeout = 10 * time.Second
+	// maxConcurrentProbes bounds how many probes run at once. Pools up to this
+	// size complete a round within a single probe timeout.
+	maxConcurrentProbes = 64
+	// defaultHealthJitter spreads probes by ±10% of their interval
+	defaultHealthJitter = 0.1
+)
+
+// HealthMonitor probes every backend on its own schedule and only flips its
+// state after a run of consistent results, so a single lost probe does not
+// take a backend out of rotation
+type HealthMonitor struct {
+	pool     ServerPool
+	interval time.Duration
+	states   map[backend.Backend]*probeState
+	mu       sync.Mutex
+}
+
+type probeState struct {
+	successes int
+	failures  int
+	next      time.Time
+}
+
+// NewHealthMonitor creates a monitor for pool. interval applies to backends
+// whose health policy does not set one.
+func NewHealthMonitor(pool ServerPool, interval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		pool:     pool,
+		interval: interval,
+		states:   make(map[backend.Backend]*probeState),
+	}
+}
+
+// Run probes backends as they become due until ctx is cancelled
+func (m *HealthMonitor) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Gracefully shutting down health check")
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		due := make([]backend.Backend, 0)
+		for _, b := range m.pool.GetBackends() {
+			if st := m.state(b, now); !now.Before(st.next) {
+				due = append(due, b)
+			}
+		}
+
+		m.probeAll(ctx, due)
+		timer.Reset(time.Until(m.nextWake()))
+	}
+}
+
+// probeAll probes the given backends concurrently and records the results
+func (m *HealthMonitor) probeAll(ctx context.Context, backends []backend.Backend) {
+	results := probeConcurrently(ctx, backends)
+	if ctx.Err() != nil {
+		return
+	}
+
+	changed := false
+	for i, b := range backends {
+		changed = m.record(b, results[i]) || changed
+	}
+
+	if o, ok := m.pool.(healthObserver); ok && changed {
+		o.healthChanged()
+	}
+}
+
+// record applies a probe result to b and schedules its next probe. It
+// reports whether the backend changed state.
+func (m *HealthMonitor) record(b backend.Backend, ok bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	policy := m.policy(b)
+	st := m.states[b]
+
+	changed := false
+	if ok {
+		st.successes++
+		st.failures = 0
+		if !b.IsAlive() && st.successes >= policy.HealthyThreshold {
+			b.SetAlive(true)
+			changed = true
+		}
+	} else {
+		st.failures++
+		st.successes = 0
+		if b.IsAlive() && st.failures >= policy.UnhealthyThreshold {
+			b.SetAlive(false)
+			changed = true
+		}
+	}
+
+	interval := policy.Interval
+	if !b.IsAlive() || st.failures > 0 {
+		interval = policy.UnhealthyInterval
+	}
+	st.next = time.Now().Add(jitter(interval, *policy.Jitter))
+
+	status := "up"
+	if !b.IsAlive() {
+		status = "down"
+	}
+	slog.Debug(
+		"URL Status",
+		"URL", b.GetURL().String(),
+		"status", status,
+		"probe", ok,
+	)
+	return changed
+}
+
+// state returns the probe state of b, scheduling a first probe within the
+// jitter window for backends seen for the first time
+func (m *HealthMonitor) state(b backend.Backend, now time.Time) *probeState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.states[b]
+	if !ok {
+		policy := m.policy(b)
+		offset := time.Duration(rand.Float64() * *policy.Jitter * float64(policy.Interval))
+		st = &probeState{next: now.Add(offset)}
+		m.states[b] = st
+	}
+	return st
+}
+
+// nextWake returns when the earliest backend is due, checking at least once
+// per interval so newly added backends are picked up
+func (m *HealthMonitor) nextWake() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wake := time.Now().Add(m.interval)
+	for _, st := range m.states {
+		if st.next.Before(wake) {
+			wake = st.next
+		}
+	}
+	return wake
+}
+
+// policy returns the health policy of b with defaults filled in
+func (m *HealthMonitor) policy(b backend.Backend) backend.HealthPolicy {
+	p := b.GetHealthPolicy()
+	if p.Interval <= 0 {
+		p.Interval = m.interval
+	}
+	if p.UnhealthyInterval <= 0 {
+		p.UnhealthyInterval = p.Interval / 2
+	}
+	if p.Jitter == nil {
+		jitter := defaultHealthJitter
+		p.Jitter = &jitter
+	}
+	if p.HealthyThreshold <= 0 {
+		p.HealthyThreshold = 1
+	}
+	if p.UnhealthyThreshold <= 0 {
+		p.UnhealthyThreshold = 1
+	}
+	return p
+}
+
+// jitter randomises d by up to ±fraction
+func jitter(d time.Duration, fraction float64) time.Duration {
+	return time.Duration(float64(d) * (1 + fraction*(2*rand.Float64()-1)))
+}
+
+// probeConcurrently probes backends on a bounded pool of workers, each probe
+// with its own timeout, and returns the results in the order of backends
+func probeConcurrently(ctx context.Context, backends []backend.Backend) []bool {
+	results := make([]bool, len(backends))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(len(backends), maxConcurrentProbes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = probe(ctx, backends[i])
+			}
+		}()
+	}
+
+	for i := range backends {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// probe runs the health checker of b, bounding it by probeTimeout unless the
+// checker sets a timeout of its own
+func probe(ctx context.Context, b backend.Backend) bool {
+	checker := b.GetHealthChecker()
+	if backend.CheckerTimeout(checker) <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, probeTimeout)
+		defer cancel()
+	}
+
+	aliveChannel := make(chan bool, 1)
+	backend.CheckBackendAlive(ctx, aliveChannel, checker, b.GetURL())
+	return <-aliveChannel
+}
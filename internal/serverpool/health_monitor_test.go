@@ -0,0 +1,222 @@
+package serverpool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func TestHealthMonitor_Thresholds(t *testing.T) {
+	sp, _ := NewServerPool(RoundRobin)
+	b := newWeightedMockBackend("http://localhost:8081", 1)
+	b.healthPolicy = backend.HealthPolicy{HealthyThreshold: 2, UnhealthyThreshold: 3}
+	sp.AddBackend(b)
+
+	m := NewHealthMonitor(sp, time.Second)
+	m.state(b, time.Now())
+
+	// A couple of failed probes are tolerated
+	for i := 0; i < 2; i++ {
+		if m.record(b, false) || !b.IsAlive() {
+			t.Fatalf("backend went down after %d failures, want 3", i+1)
+		}
+	}
+
+	// A success in between resets the failure count
+	m.record(b, true)
+	m.record(b, false)
+	m.record(b, false)
+	if !b.IsAlive() {
+		t.Fatal("backend went down although failures were not consecutive")
+	}
+
+	if !m.record(b, false) || b.IsAlive() {
+		t.Fatal("backend still up after 3 consecutive failures")
+	}
+
+	if m.record(b, true) || b.IsAlive() {
+		t.Fatal("backend came back after a single success, want 2")
+	}
+	if !m.record(b, true) || !b.IsAlive() {
+		t.Fatal("backend still down after 2 consecutive successes")
+	}
+}
+
+func TestHealthMonitor_Schedule(t *testing.T) {
+	sp, _ := NewServerPool(RoundRobin)
+	b := newWeightedMockBackend("http://localhost:8081", 1)
+	jitter := 0.2
+	b.healthPolicy = backend.HealthPolicy{
+		Interval:          10 * time.Second,
+		UnhealthyInterval: time.Second,
+		Jitter:            &jitter,
+	}
+	sp.AddBackend(b)
+
+	m := NewHealthMonitor(sp, time.Minute)
+
+	// The first probe is spread within the jitter window
+	st := m.state(b, time.Now())
+	if delay := time.Until(st.next); delay > 2*time.Second {
+		t.Errorf("first probe in %v, want within 2s", delay)
+	}
+
+	within := func(d, want time.Duration) bool {
+		return d >= want*79/100 && d <= want*121/100
+	}
+
+	m.record(b, true)
+	if delay := time.Until(st.next); !within(delay, 10*time.Second) {
+		t.Errorf("healthy backend probed again in %v, want 10s ±20%%", delay)
+	}
+
+	// Down backends are re-probed on the faster schedule
+	m.record(b, false)
+	if delay := time.Until(st.next); !within(delay, time.Second) {
+		t.Errorf("down backend probed again in %v, want 1s ±20%%", delay)
+	}
+}
+
+func TestHealthMonitor_Defaults(t *testing.T) {
+	sp, _ := NewServerPool(RoundRobin)
+	b := newWeightedMockBackend("http://localhost:8081", 1)
+	m := NewHealthMonitor(sp, 10*time.Second)
+
+	jitter := defaultHealthJitter
+	want := backend.HealthPolicy{
+		Interval:           10 * time.Second,
+		UnhealthyInterval:  5 * time.Second,
+		Jitter:             &jitter,
+		HealthyThreshold:   1,
+		UnhealthyThreshold: 1,
+	}
+	if got := m.policy(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("policy() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHealthMonitor_NoJitter(t *testing.T) {
+	sp, _ := NewServerPool(RoundRobin)
+	b := newWeightedMockBackend("http://localhost:8081", 1)
+	jitter := 0.0
+	b.healthPolicy = backend.HealthPolicy{Interval: 10 * time.Second, Jitter: &jitter}
+	sp.AddBackend(b)
+
+	m := NewHealthMonitor(sp, time.Minute)
+	if got := *m.policy(b).Jitter; got != 0 {
+		t.Fatalf("jitter = %v, want 0", got)
+	}
+
+	// Without jitter the first probe is due at once and the next one after
+	// exactly one interval
+	now := time.Now()
+	st := m.state(b, now)
+	if !st.next.Equal(now) {
+		t.Errorf("first probe delayed by %v, want none", st.next.Sub(now))
+	}
+
+	m.record(b, true)
+	if delay := time.Until(st.next); delay > 10*time.Second || delay < 9*time.Second {
+		t.Errorf("backend probed again in %v, want 10s", delay)
+	}
+}
+
+// deadlineTransport records the deadline of the request it answers
+type deadlineTransport struct {
+	deadline time.Time
+}
+
+func (d *deadlineTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	d.deadline, _ = r.Context().Deadline()
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+}
+
+func TestProbeTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{"checker without a timeout", 0, probeTimeout},
+		{"checker timeout above the cap", 3 * probeTimeout, 3 * probeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &deadlineTransport{}
+			b := newWeightedMockBackend("http://localhost:8081", 1)
+			b.healthChecker = &backend.HTTPHealthChecker{
+				Timeout: tt.timeout,
+				Client:  &http.Client{Transport: transport},
+			}
+
+			if !probe(context.Background(), b) {
+				t.Fatal("probe() = false, want true")
+			}
+			if got := time.Until(transport.deadline); got < tt.want-time.Second || got > tt.want {
+				t.Errorf("probe deadline in %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthMonitor_Run(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	b := backend.NewBackend(u, httputil.NewSingleHostReverseProxy(u),
+		backend.WithHealthChecker(&backend.HTTPHealthChecker{Path: "/"}),
+		backend.WithHealthPolicy(backend.HealthPolicy{
+			Interval:           20 * time.Millisecond,
+			UnhealthyThreshold: 2,
+		}),
+	)
+
+	sp, _ := NewServerPool(RoundRobin)
+	sp.AddBackend(b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewHealthMonitor(sp, time.Second).Run(ctx)
+		close(done)
+	}()
+
+	waitFor := func(alive bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for b.IsAlive() != alive {
+			if time.Now().After(deadline) {
+				t.Fatalf("backend alive = %v, want %v", b.IsAlive(), alive)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	healthy.Store(false)
+	waitFor(false)
+	healthy.Store(true)
+	waitFor(true)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("HealthMonitor did not stop after context cancellation")
+	}
+}
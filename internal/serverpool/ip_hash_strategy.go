@@ -0,0 +1,10 @@
+package serverpool
+
+// ipHashServerPool pins each client address to a backend on a hash ring,
+// whatever hash key the other strategies are configured with. Adding or
+// removing a backend only moves the clients of its own arcs, and when a
+// client's backend is unavailable the next one clockwise takes over, so other
+// clients keep their backend.
+type ipHashServerPool struct {
+	*chServerPool
+}
@@ -0,0 +1,97 @@
+package serverpool
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func TestIPHashServerPool(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"10.0.0.0/8"}, "")
+	pool, backends := newTestHashPool(t, IPHash, 4, WithTrustedProxies(proxies))
+
+	// Test empty pool
+	empty, _ := newTestHashPool(t, IPHash, 0)
+	if got := empty.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	direct := httptest.NewRequest("GET", "/", nil)
+	direct.RemoteAddr = "198.51.100.7:1234"
+
+	// The same client behind a trusted proxy maps to the same backend
+	proxied := httptest.NewRequest("GET", "/", nil)
+	proxied.RemoteAddr = "10.0.0.1:5678"
+	proxied.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+	owner := NextValidPeer(pool, direct)
+	if owner == nil {
+		t.Fatal("GetNextValidPeerForRequest() = nil, want non-nil")
+	}
+	if got := NextValidPeer(pool, proxied); got != owner {
+		t.Errorf("proxied request went to %v, want %v", got.GetURL(), owner.GetURL())
+	}
+
+	// Clients are spread over the backends
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.100.%d:1234", i)
+		seen[NextValidPeer(pool, req).GetURL().String()] = true
+	}
+	if len(seen) != len(backends) {
+		t.Errorf("clients reached %d backends, want %d", len(seen), len(backends))
+	}
+
+	// A down backend hands its clients to another one
+	owner.SetAlive(false)
+	if got := NextValidPeer(pool, direct); got == nil || got == owner {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want another alive backend", got)
+	}
+
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+	if got := NextValidPeer(pool, direct); got != nil {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestIPHashServerPool_MinimalRemapping(t *testing.T) {
+	const clients = 10000
+	pool, _ := newTestHashPool(t, IPHash, 4)
+
+	request := func(i int) *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = fmt.Sprintf("198.51.%d.%d:1234", i/256, i%256)
+		return req
+	}
+
+	before := make([]backend.Backend, clients)
+	for i := range before {
+		before[i] = NextValidPeer(pool, request(i))
+	}
+
+	added := newWeightedMockBackend("http://10.0.0.5:8080", 1)
+	pool.AddBackend(added)
+
+	moved := 0
+	for i := range before {
+		got := NextValidPeer(pool, request(i))
+		if got == before[i] {
+			continue
+		}
+		moved++
+		if got != added {
+			t.Fatalf("client %d moved between existing backends", i)
+		}
+	}
+
+	// Ideally 1/5 of the clients move to the new backend
+	if ratio := float64(moved) / clients; ratio < 0.1 || ratio > 0.3 {
+		t.Errorf("remapped %.2f of clients, want about 0.20", ratio)
+	}
+}
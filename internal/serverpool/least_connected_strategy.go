@@ -11,27 +11,34 @@ type lcServerPool struct {
 	mux      sync.RWMutex
 }
 
+// GetNextValidPeer returns the available peer with the fewest active
+// connections. Peers in slow start count as more loaded, in inverse
+// proportion to their slow start factor, so they only take a request once
+// the others are busy enough.
 func (s *lcServerPool) GetNextValidPeer() backend.Backend {
-	var leastConnectedPeer backend.Backend
-	for _, b := range s.backends {
-		if b.IsAlive() {
-			leastConnectedPeer = b
-			break
-		}
-	}
+	s.mux.RLock()
+	defer s.mux.RUnlock()
 
+	var leastConnectedPeer backend.Backend
+	leastLoad := 0.0
 	for _, b := range s.backends {
-		if !b.IsAlive() {
+		if !isAvailable(b) {
 			continue
 		}
-		if leastConnectedPeer.GetActiveConnections() > b.GetActiveConnections() {
+
+		load := float64(b.GetActiveConnections()+1) / b.GetSlowStartFactor()
+		if leastConnectedPeer == nil || load < leastLoad {
 			leastConnectedPeer = b
+			leastLoad = load
 		}
 	}
 	return leastConnectedPeer
 }
 
 func (s *lcServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	s.backends = append(s.backends, b)
 }
 
@@ -65,3 +65,25 @@ func TestLeastConnectedServerPool(t *testing.T) {
 		t.Errorf("GetBackends() returned %v backends, want %v", len(backends), len(urls))
 	}
 }
+
+func TestLeastConnectedServerPool_SlowStart(t *testing.T) {
+	pool := &lcServerPool{}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	b.slowStartFactor = 0.25
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+
+	// A warming peer counts as four times as loaded, so it is only picked
+	// once the other peer has more than three connections
+	a.activeConnections = 3
+	if got := pool.GetNextValidPeer(); got != a {
+		t.Errorf("GetNextValidPeer() = %v, want %v", got.GetURL(), a.GetURL())
+	}
+
+	a.activeConnections = 4
+	if got := pool.GetNextValidPeer(); got != b {
+		t.Errorf("GetNextValidPeer() = %v, want %v", got.GetURL(), b.GetURL())
+	}
+}
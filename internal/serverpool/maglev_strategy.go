@@ -0,0 +1,148 @@
+package serverpool
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// maglevTableSize must be prime so every backend's permutation covers the
+// whole table. It should be well above 100× the number of backends.
+const maglevTableSize = 65537
+
+// maglevServerPool implements Maglev hashing (Eisenbud et al., NSDI '16).
+// Alive backends take turns claiming slots of a fixed lookup table following
+// their own permutation, which gives each an almost equal share and moves few
+// slots when membership changes. Selection is a single table lookup.
+type maglevServerPool struct {
+	backends []backend.Backend
+	table    []int
+	// owners is the number of backends holding slots of the table
+	owners int
+	key    HashKey
+	mux    sync.RWMutex
+}
+
+func (s *maglevServerPool) GetNextValidPeer() backend.Backend {
+	return s.lookup(0)
+}
+
+func (s *maglevServerPool) GetNextValidPeerForRequest(r *http.Request) backend.Backend {
+	return s.lookup(HashString(s.key.Extract(r)))
+}
+
+func (s *maglevServerPool) lookup(h uint64) backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if len(s.backends) == 0 {
+		return nil
+	}
+
+	start := h % maglevTableSize
+	if owner := s.table[start]; owner >= 0 && isAvailable(s.backends[owner]) {
+		return s.backends[owner]
+	}
+
+	// The owner may be saturated or the table stale. Probing the following
+	// slots hands its keys to the other backends in proportion to their
+	// share of the table instead of piling them onto a single neighbour.
+	rejected := make([]bool, len(s.backends))
+	remaining := s.owners
+	for i := uint64(0); i < maglevTableSize && remaining > 0; i++ {
+		owner := s.table[(start+i)%maglevTableSize]
+		if owner < 0 || rejected[owner] {
+			continue
+		}
+		if b := s.backends[owner]; isAvailable(b) {
+			return b
+		}
+		rejected[owner] = true
+		remaining--
+	}
+
+	// Nothing in the table can serve; try backends it does not list in a
+	// fixed order so the choice stays deterministic
+	for i, b := range s.backends {
+		if !rejected[i] && isAvailable(b) {
+			return b
+		}
+	}
+	return nil
+}
+
+// rebuild repopulates the lookup table from the healthy backends. Callers must
+// hold the write lock.
+func (s *maglevServerPool) rebuild() {
+	if s.table == nil {
+		s.table = make([]int, maglevTableSize)
+	}
+	for i := range s.table {
+		s.table[i] = -1
+	}
+
+	type permutation struct {
+		owner, offset, skip, next uint64
+	}
+
+	perms := make([]permutation, 0, len(s.backends))
+	for i, b := range s.backends {
+		if !isHealthy(b) {
+			continue
+		}
+
+		id := b.GetURL().String()
+		perms = append(perms, permutation{
+			owner:  uint64(i),
+			offset: HashString(id+"#offset") % maglevTableSize,
+			skip:   HashString(id+"#skip")%(maglevTableSize-1) + 1,
+		})
+	}
+
+	s.owners = len(perms)
+	if len(perms) == 0 {
+		return
+	}
+
+	for filled := 0; ; {
+		for i := range perms {
+			p := &perms[i]
+			slot := (p.offset + p.next*p.skip) % maglevTableSize
+			for s.table[slot] >= 0 {
+				p.next++
+				slot = (p.offset + p.next*p.skip) % maglevTableSize
+			}
+
+			s.table[slot] = int(p.owner)
+			p.next++
+			filled++
+			if filled == maglevTableSize {
+				return
+			}
+		}
+	}
+}
+
+func (s *maglevServerPool) healthChanged() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.rebuild()
+}
+
+func (s *maglevServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+	s.rebuild()
+}
+
+func (s *maglevServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *maglevServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
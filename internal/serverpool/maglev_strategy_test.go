@@ -0,0 +1,196 @@
+package serverpool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func TestMaglevServerPool(t *testing.T) {
+	key := HashKey{Source: HashByHeader, Name: "X-User"}
+	pool, backends := newTestHashPool(t, Maglev, 3, WithHashKey(key))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-User", "alice")
+
+	first := NextValidPeer(pool, req)
+	if first == nil {
+		t.Fatal("GetNextValidPeerForRequest() = nil, want non-nil")
+	}
+	for i := 0; i < 10; i++ {
+		if got := NextValidPeer(pool, req); got != first {
+			t.Fatalf("GetNextValidPeerForRequest() = %v, want %v", got.GetURL(), first.GetURL())
+		}
+	}
+
+	// A dead owner is skipped even before the table is rebuilt
+	first.SetAlive(false)
+	if got := NextValidPeer(pool, req); got == nil || got == first {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want another alive backend", got)
+	}
+
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+	pool.(healthObserver).healthChanged()
+	if got := NextValidPeer(pool, req); got != nil {
+		t.Errorf("GetNextValidPeerForRequest() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestMaglevServerPool_FallbackSpreadsLoad(t *testing.T) {
+	const keys = 10000
+	pool, backends := newTestHashPool(t, Maglev, 4, WithHashKey(HashKey{Source: HashByPath}))
+
+	// Backends with an open circuit stay in the table, so their keys must be
+	// spread by the lookup itself
+	backends[0].circuitState = backend.CircuitOpen
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < keys; i++ {
+		counts[NextValidPeer(pool, httptest.NewRequest("GET", fmt.Sprintf("/item/%d", i), nil))]++
+	}
+
+	if counts[backends[0]] != 0 {
+		t.Errorf("backend with an open circuit got %d keys, want 0", counts[backends[0]])
+	}
+	for _, b := range backends[1:] {
+		if share := float64(counts[b]) / keys; share < 0.28 || share > 0.39 {
+			t.Errorf("backend %v got %.2f of keys, want about 0.33", b.GetURL(), share)
+		}
+	}
+}
+
+func TestMaglevServerPool_Empty(t *testing.T) {
+	pool, _ := newTestHashPool(t, Maglev, 0)
+
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+}
+
+func TestMaglevServerPool_EvenTable(t *testing.T) {
+	pool, _ := newTestHashPool(t, Maglev, 5)
+	mp := pool.(*maglevServerPool)
+
+	counts := make([]int, len(mp.backends))
+	for _, owner := range mp.table {
+		counts[owner]++
+	}
+
+	// Maglev guarantees shares within a few percent of M/N
+	want := maglevTableSize / len(counts)
+	for i, c := range counts {
+		if c < want*95/100 || c > want*105/100 {
+			t.Errorf("backend %d owns %d slots, want about %d", i, c, want)
+		}
+	}
+}
+
+func TestMaglevServerPool_MinimalDisruption(t *testing.T) {
+	pool, backends := newTestHashPool(t, Maglev, 5)
+	mp := pool.(*maglevServerPool)
+
+	before := append([]int(nil), mp.table...)
+
+	// Removing a backend from the table reassigns its slots and only a few others
+	backends[2].SetAlive(false)
+	mp.healthChanged()
+
+	moved := 0
+	for i, owner := range mp.table {
+		if owner == 2 {
+			t.Fatal("dead backend still owns table slots after rebuild")
+		}
+		if before[i] != 2 && owner != before[i] {
+			moved++
+		}
+	}
+	if ratio := float64(moved) / maglevTableSize; ratio > 0.1 {
+		t.Errorf("%.2f of slots owned by surviving backends moved, want < 0.10", ratio)
+	}
+}
+
+func TestHealthCheckRebuildsMaglevTable(t *testing.T) {
+	sp, err := NewServerPool(Maglev)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	alive := newWeightedMockBackend(up.URL, 1)
+	dead := newWeightedMockBackend(down.URL, 1)
+	sp.AddBackend(alive)
+	sp.AddBackend(dead)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	HealthCheck(ctx, sp)
+
+	for i, owner := range sp.(*maglevServerPool).table {
+		if owner != 0 {
+			t.Fatalf("slot %d owned by %d after health check, want only the alive backend", i, owner)
+		}
+	}
+}
+
+// distributionSpread sends keys through pool and returns the busiest
+// backend's share relative to a perfectly even split
+func distributionSpread(pool ServerPool, keys int) float64 {
+	counts := make(map[backend.Backend]int)
+	req := httptest.NewRequest("GET", "/", nil)
+	for i := 0; i < keys; i++ {
+		req.URL.Path = fmt.Sprintf("/item/%d", i)
+		counts[NextValidPeer(pool, req)]++
+	}
+
+	most := 0
+	for _, c := range counts {
+		most = max(most, c)
+	}
+	return float64(most) / (float64(keys) / float64(pool.GetServerPoolSize()))
+}
+
+func benchmarkHashStrategy(b *testing.B, strategy LBStrategy, size int) {
+	pool, err := NewServerPool(strategy, WithHashKey(HashKey{Source: HashByPath}))
+	if err != nil {
+		b.Fatalf("Failed to create server pool: %v", err)
+	}
+	for i := 0; i < size; i++ {
+		pool.AddBackend(newWeightedMockBackend(fmt.Sprintf("http://10.0.%d.%d:8080", i/256, i%256), 1))
+	}
+
+	spread := distributionSpread(pool, 100000)
+
+	req := httptest.NewRequest("GET", "/item/42", nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NextValidPeer(pool, req)
+	}
+	b.ReportMetric(spread, "max/mean")
+}
+
+func BenchmarkMaglev(b *testing.B) {
+	for _, size := range []int{10, 100} {
+		b.Run(fmt.Sprintf("backends=%d", size), func(b *testing.B) {
+			benchmarkHashStrategy(b, Maglev, size)
+		})
+	}
+}
+
+func BenchmarkConsistentHash(b *testing.B) {
+	for _, size := range []int{10, 100} {
+		b.Run(fmt.Sprintf("backends=%d", size), func(b *testing.B) {
+			benchmarkHashStrategy(b, ConsistentHash, size)
+		})
+	}
+}
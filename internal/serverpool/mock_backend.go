@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"time"
 
 	"github.com/darshan-rambhia/eisodos/internal/backend"
 )
@@ -12,6 +13,15 @@ type mockBackend struct {
 	url               *url.URL
 	proxy             *httputil.ReverseProxy
 	activeConnections int
+	weight            int
+	maxConns          int
+	latency           time.Duration
+	healthChecker     backend.HealthChecker
+	healthPolicy      backend.HealthPolicy
+	outcomes          backend.Outcomes
+	ejected           bool
+	circuitState      backend.CircuitState
+	slowStartFactor   float64
 	alive             bool
 }
 
@@ -23,6 +33,56 @@ func (b *mockBackend) GetActiveConnections() int {
 	return b.activeConnections
 }
 
+func (b *mockBackend) GetWeight() int {
+	return b.weight
+}
+
+func (b *mockBackend) IsSaturated() bool {
+	return b.maxConns > 0 && b.activeConnections >= b.maxConns
+}
+
+func (b *mockBackend) GetLatency() time.Duration {
+	return b.latency
+}
+
+func (b *mockBackend) GetHealthChecker() backend.HealthChecker {
+	return b.healthChecker
+}
+
+func (b *mockBackend) GetHealthPolicy() backend.HealthPolicy {
+	return b.healthPolicy
+}
+
+func (b *mockBackend) GetOutcomes() backend.Outcomes {
+	return b.outcomes
+}
+
+func (b *mockBackend) SetEjected(ejected bool) {
+	b.ejected = ejected
+}
+
+func (b *mockBackend) IsEjected() bool {
+	return b.ejected
+}
+
+func (b *mockBackend) GetCircuitState() backend.CircuitState {
+	return b.circuitState
+}
+
+func (b *mockBackend) IsCircuitOpen() bool {
+	return b.circuitState == backend.CircuitOpen
+}
+
+func (b *mockBackend) StartSlowStart() {}
+
+// GetSlowStartFactor returns slowStartFactor, with zero meaning full weight
+func (b *mockBackend) GetSlowStartFactor() float64 {
+	if b.slowStartFactor == 0 {
+		return 1
+	}
+	return b.slowStartFactor
+}
+
 func (b *mockBackend) IsAlive() bool {
 	return b.alive
 }
@@ -37,8 +97,9 @@ func (b *mockBackend) Serve(w http.ResponseWriter, r *http.Request) {
 
 func newMockBackend(url *url.URL, proxy *httputil.ReverseProxy) backend.Backend {
 	return &mockBackend{
-		url:   url,
-		proxy: proxy,
-		alive: true,
+		url:    url,
+		proxy:  proxy,
+		weight: 1,
+		alive:  true,
 	}
 }
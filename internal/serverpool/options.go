@@ -0,0 +1,71 @@
+package serverpool
+
+import "math/rand/v2"
+
+// Option configures strategy specific settings of a ServerPool
+type Option func(*options)
+
+type options struct {
+	hashKey      HashKey
+	virtualNodes int
+	loadEpsilon  float64
+	rnd          *rand.Rand
+	proxies      *TrustedProxies
+}
+
+const (
+	defaultVirtualNodes = 160
+	defaultLoadEpsilon  = 0.25
+)
+
+func defaultOptions() options {
+	return options{
+		hashKey:      HashKey{Source: HashByClientIP},
+		virtualNodes: defaultVirtualNodes,
+		loadEpsilon:  defaultLoadEpsilon,
+	}
+}
+
+// WithHashKey sets the request attribute hashed by hash based strategies
+func WithHashKey(key HashKey) Option {
+	return func(o *options) {
+		o.hashKey = key
+	}
+}
+
+// WithVirtualNodes sets how many points each backend occupies on the hash ring.
+// Values below 1 keep the default.
+func WithVirtualNodes(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.virtualNodes = n
+		}
+	}
+}
+
+// WithLoadEpsilon sets ε for bounded-load hashing: a backend may carry at most
+// (1+ε) times the average load. Zero balances the load perfectly at the cost
+// of affinity; negative values keep the default.
+func WithLoadEpsilon(epsilon float64) Option {
+	return func(o *options) {
+		if epsilon >= 0 {
+			o.loadEpsilon = epsilon
+		}
+	}
+}
+
+// WithRand sets the random generator of randomised strategies, making their
+// picks reproducible. By default a shared, unseeded generator is used.
+func WithRand(rnd *rand.Rand) Option {
+	return func(o *options) {
+		o.rnd = rnd
+	}
+}
+
+// WithTrustedProxies sets the proxies whose forwarding headers are used to
+// determine the client address
+func WithTrustedProxies(proxies *TrustedProxies) Option {
+	return func(o *options) {
+		o.proxies = proxies
+	}
+}
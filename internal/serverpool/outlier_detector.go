@@ -0,0 +1,237 @@
+package serverpool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// OutlierPolicy controls passive health checking. Zero fields fall back to
+// the defaults noted on each field, except that ConsecutiveFailures stays
+// disabled when only FailureRateMargin is set.
+type OutlierPolicy struct {
+	// Interval between evaluations of the collected outcomes. Defaults to 1s.
+	Interval time.Duration
+	// ConsecutiveFailures ejects a backend after this many failed requests
+	// in a row. Defaults to 5.
+	ConsecutiveFailures int
+	// FailureRateMargin ejects a backend whose failure rate over an interval
+	// exceeds the pool's by more than this fraction, e.g. 0.2 for 20 points
+	FailureRateMargin float64
+	// MinRequests a backend must serve in an interval before its failure rate
+	// is compared. Defaults to 10.
+	MinRequests int
+	// BaseEjectionTime is doubled on every repeated ejection. Defaults to 30s.
+	BaseEjectionTime time.Duration
+	// MaxEjectionTime caps the ejection time. Defaults to 5m.
+	MaxEjectionTime time.Duration
+	// MaxEjectionPercent of the pool may be ejected at once, though a single
+	// backend can always be. Defaults to 10.
+	MaxEjectionPercent int
+}
+
+const (
+	defaultOutlierInterval            = time.Second
+	defaultOutlierConsecutiveFailures = 5
+	defaultOutlierMinRequests         = 10
+	defaultBaseEjectionTime           = 30 * time.Second
+	defaultMaxEjectionTime            = 5 * time.Minute
+	defaultMaxEjectionPercent         = 10
+)
+
+// OutlierDetector ejects backends that fail live traffic, complementing the
+// active probes of HealthMonitor which may be too infrequent to notice
+// failures that only show up under load. Ejected backends return to rotation
+// once their ejection time has passed.
+type OutlierDetector struct {
+	pool   ServerPool
+	policy OutlierPolicy
+	states map[backend.Backend]*outlierState
+	mu     sync.Mutex
+}
+
+type outlierState struct {
+	// last holds the outcomes at the previous evaluation
+	last backend.Outcomes
+	// ejections counts recent ejections and sets the next ejection time
+	ejections    int
+	ejectedUntil time.Time
+}
+
+// NewOutlierDetector creates a detector for pool
+func NewOutlierDetector(pool ServerPool, policy OutlierPolicy) *OutlierDetector {
+	if policy.Interval <= 0 {
+		policy.Interval = defaultOutlierInterval
+	}
+	if policy.ConsecutiveFailures <= 0 && policy.FailureRateMargin <= 0 {
+		policy.ConsecutiveFailures = defaultOutlierConsecutiveFailures
+	}
+	if policy.MinRequests <= 0 {
+		policy.MinRequests = defaultOutlierMinRequests
+	}
+	if policy.BaseEjectionTime <= 0 {
+		policy.BaseEjectionTime = defaultBaseEjectionTime
+	}
+	if policy.MaxEjectionTime <= 0 {
+		policy.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	if policy.MaxEjectionTime < policy.BaseEjectionTime {
+		policy.MaxEjectionTime = policy.BaseEjectionTime
+	}
+	if policy.MaxEjectionPercent <= 0 {
+		policy.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+
+	return &OutlierDetector{
+		pool:   pool,
+		policy: policy,
+		states: make(map[backend.Backend]*outlierState),
+	}
+}
+
+// Run evaluates the pool every interval until ctx is cancelled
+func (d *OutlierDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Gracefully shutting down outlier detection")
+			return
+		case now := <-ticker.C:
+			d.evaluate(now)
+		}
+	}
+}
+
+// evaluate returns backends whose ejection has expired and ejects the ones
+// that failed since the previous evaluation
+func (d *OutlierDetector) evaluate(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	backends := d.pool.GetBackends()
+	deltas := make([]backend.Outcomes, len(backends))
+
+	changed := false
+	ejected := 0
+	var poolRequests, poolFailures uint64
+	for i, b := range backends {
+		st, ok := d.states[b]
+		if !ok {
+			st = &outlierState{}
+			d.states[b] = st
+		}
+
+		current := b.GetOutcomes()
+		deltas[i] = backend.Outcomes{
+			Requests:            current.Requests - st.last.Requests,
+			Failures:            current.Failures - st.last.Failures,
+			ConsecutiveFailures: current.ConsecutiveFailures,
+		}
+		st.last = current
+
+		if b.IsEjected() && !now.Before(st.ejectedUntil) {
+			b.SetEjected(false)
+			changed = true
+			slog.Info("Backend returned from ejection", "URL", b.GetURL().String())
+		}
+
+		if b.IsEjected() {
+			ejected++
+		} else if deltas[i].Requests >= uint64(d.policy.MinRequests) {
+			poolRequests += deltas[i].Requests
+			poolFailures += deltas[i].Failures
+		}
+	}
+
+	var poolRate float64
+	if poolRequests > 0 {
+		poolRate = float64(poolFailures) / float64(poolRequests)
+	}
+
+	for i, b := range backends {
+		if b.IsEjected() {
+			continue
+		}
+
+		st := d.states[b]
+		reason := d.outlier(deltas[i], poolRate)
+		if reason == "" {
+			// Backends that serve a whole interval without failing earn back
+			// shorter ejections
+			if st.ejections > 0 && deltas[i].Requests > 0 && deltas[i].Failures == 0 {
+				st.ejections--
+			}
+			continue
+		}
+
+		if ejected > 0 && (ejected+1)*100 > d.policy.MaxEjectionPercent*len(backends) {
+			slog.Warn("Outlier not ejected, max ejection percent reached", "URL", b.GetURL().String(), "reason", reason)
+			continue
+		}
+
+		st.ejections++
+		duration := d.ejectionTime(st.ejections)
+		st.ejectedUntil = now.Add(duration)
+		b.SetEjected(true)
+		ejected++
+		changed = true
+
+		slog.Warn(
+			"Backend ejected",
+			"URL", b.GetURL().String(),
+			"reason", reason,
+			"duration", duration,
+		)
+	}
+
+	// Forget backends no longer in the pool
+	if len(d.states) > len(backends) {
+		present := make(map[backend.Backend]bool, len(backends))
+		for _, b := range backends {
+			present[b] = true
+		}
+		for b := range d.states {
+			if !present[b] {
+				delete(d.states, b)
+			}
+		}
+	}
+
+	if o, ok := d.pool.(healthObserver); ok && changed {
+		o.healthChanged()
+	}
+}
+
+// outlier returns why a backend with the given interval outcomes should be
+// ejected, or "" if it should not
+func (d *OutlierDetector) outlier(delta backend.Outcomes, poolRate float64) string {
+	// The streak only counts if it grew, so a backend returning from ejection
+	// is not ejected again before it has served anything
+	if d.policy.ConsecutiveFailures > 0 && delta.Failures > 0 &&
+		delta.ConsecutiveFailures >= uint64(d.policy.ConsecutiveFailures) {
+		return "consecutive failures"
+	}
+
+	if d.policy.FailureRateMargin > 0 && delta.Requests >= uint64(d.policy.MinRequests) {
+		rate := float64(delta.Failures) / float64(delta.Requests)
+		if rate-poolRate > d.policy.FailureRateMargin {
+			return "failure rate"
+		}
+	}
+	return ""
+}
+
+// ejectionTime doubles the base ejection time for every repeated ejection
+func (d *OutlierDetector) ejectionTime(ejections int) time.Duration {
+	duration := d.policy.BaseEjectionTime
+	for i := 1; i < ejections && duration < d.policy.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	return min(duration, d.policy.MaxEjectionTime)
+}
@@ -0,0 +1,177 @@
+package serverpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func newOutlierTestPool(t *testing.T, n int) (ServerPool, []*mockBackend) {
+	t.Helper()
+
+	sp, err := NewServerPool(RoundRobin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backends := make([]*mockBackend, n)
+	for i := range backends {
+		backends[i] = newWeightedMockBackend("http://localhost:"+string(rune('1'+i))+"000", 1)
+		sp.AddBackend(backends[i])
+	}
+	return sp, backends
+}
+
+// serve records requests and failures against b
+func serve(b *mockBackend, requests, failures int) {
+	b.outcomes.Requests += uint64(requests)
+	b.outcomes.Failures += uint64(failures)
+	if failures == requests {
+		b.outcomes.ConsecutiveFailures += uint64(failures)
+	} else {
+		b.outcomes.ConsecutiveFailures = 0
+	}
+}
+
+func TestOutlierDetector_ConsecutiveFailures(t *testing.T) {
+	sp, backends := newOutlierTestPool(t, 2)
+	d := NewOutlierDetector(sp, OutlierPolicy{
+		ConsecutiveFailures: 3,
+		BaseEjectionTime:    10 * time.Second,
+		MaxEjectionPercent:  50,
+	})
+
+	now := time.Now()
+	serve(backends[0], 2, 2)
+	d.evaluate(now)
+	if backends[0].IsEjected() {
+		t.Fatal("backend ejected after 2 consecutive failures, want 3")
+	}
+
+	serve(backends[0], 1, 1)
+	d.evaluate(now.Add(time.Second))
+	if !backends[0].IsEjected() {
+		t.Fatal("backend not ejected after 3 consecutive failures")
+	}
+	if backends[1].IsEjected() {
+		t.Error("healthy backend was ejected")
+	}
+
+	// Ejected backends are skipped by the strategy
+	for i := 0; i < 4; i++ {
+		if peer := sp.GetNextValidPeer(); peer != backends[1] {
+			t.Fatalf("GetNextValidPeer() = %v, want the healthy backend", peer.GetURL())
+		}
+	}
+
+	// The backend returns once its ejection time has passed and is not ejected
+	// again for its old streak
+	d.evaluate(now.Add(11 * time.Second))
+	if backends[0].IsEjected() {
+		t.Fatal("backend still ejected after its ejection time")
+	}
+	d.evaluate(now.Add(12 * time.Second))
+	if backends[0].IsEjected() {
+		t.Fatal("backend ejected again without new failures")
+	}
+}
+
+func TestOutlierDetector_ExponentialEjectionTime(t *testing.T) {
+	sp, backends := newOutlierTestPool(t, 2)
+	d := NewOutlierDetector(sp, OutlierPolicy{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    10 * time.Second,
+		MaxEjectionTime:     30 * time.Second,
+		MaxEjectionPercent:  50,
+	})
+
+	now := time.Now()
+	for _, want := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second} {
+		serve(backends[0], 1, 1)
+		d.evaluate(now)
+		if !backends[0].IsEjected() {
+			t.Fatal("backend not ejected")
+		}
+
+		if got := d.states[backends[0]].ejectedUntil.Sub(now); got != want {
+			t.Errorf("ejected for %v, want %v", got, want)
+		}
+
+		now = now.Add(want)
+		d.evaluate(now)
+		if backends[0].IsEjected() {
+			t.Fatal("backend still ejected after its ejection time")
+		}
+	}
+}
+
+func TestOutlierDetector_FailureRate(t *testing.T) {
+	sp, backends := newOutlierTestPool(t, 4)
+	d := NewOutlierDetector(sp, OutlierPolicy{
+		FailureRateMargin:  0.2,
+		MinRequests:        10,
+		MaxEjectionPercent: 50,
+	})
+
+	// 40% failures against a pool rate of 14%
+	serve(backends[0], 100, 40)
+	serve(backends[1], 100, 5)
+	serve(backends[2], 100, 10)
+	serve(backends[3], 5, 5) // below MinRequests
+
+	d.evaluate(time.Now())
+
+	for i, want := range []bool{true, false, false, false} {
+		if got := backends[i].IsEjected(); got != want {
+			t.Errorf("backend %d ejected = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestOutlierDetector_MaxEjectionPercent(t *testing.T) {
+	sp, backends := newOutlierTestPool(t, 4)
+	d := NewOutlierDetector(sp, OutlierPolicy{
+		ConsecutiveFailures: 1,
+		MaxEjectionPercent:  10,
+	})
+
+	for _, b := range backends[:3] {
+		serve(b, 1, 1)
+	}
+	d.evaluate(time.Now())
+
+	ejected := 0
+	for _, b := range backends {
+		if b.IsEjected() {
+			ejected++
+		}
+	}
+
+	// 10% of 4 backends rounds down to none, but one may always be ejected
+	if ejected != 1 {
+		t.Errorf("%d backends ejected, want 1", ejected)
+	}
+}
+
+func TestOutlierDetector_NotifiesPool(t *testing.T) {
+	sp, err := NewServerPool(Maglev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	sp.AddBackend(a)
+	sp.AddBackend(b)
+
+	d := NewOutlierDetector(sp, OutlierPolicy{ConsecutiveFailures: 1, MaxEjectionPercent: 50})
+	serve(a, 1, 1)
+	d.evaluate(time.Now())
+
+	// The Maglev table is rebuilt without the ejected backend
+	for _, owner := range sp.(*maglevServerPool).table {
+		if sp.GetBackends()[owner] == backend.Backend(a) {
+			t.Fatal("ejected backend still owns Maglev table entries")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package serverpool
+
+import (
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// p2cSamples bounds how many random pairs are tried before falling back to a
+// linear scan, which only matters when most of the pool is unavailable
+const p2cSamples = 3
+
+// p2cServerPool implements the power of two choices: it samples two random
+// peers and picks the one with fewer active connections. This comes close to
+// least-connected balancing while touching two backends per request.
+type p2cServerPool struct {
+	backends []backend.Backend
+	rnd      *randSource
+	mux      sync.RWMutex
+}
+
+func (s *p2cServerPool) GetNextValidPeer() backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	n := len(s.backends)
+	if n == 0 {
+		return nil
+	}
+	if n == 1 {
+		if isAvailable(s.backends[0]) {
+			return s.backends[0]
+		}
+		return nil
+	}
+
+	for attempt := 0; attempt < p2cSamples; attempt++ {
+		i := s.rnd.IntN(n)
+		j := s.rnd.IntN(n - 1)
+		if j >= i {
+			j++
+		}
+
+		a, b := s.backends[i], s.backends[j]
+		aOK, bOK := isAvailable(a), isAvailable(b)
+		switch {
+		case aOK && bOK:
+			if b.GetActiveConnections() < a.GetActiveConnections() {
+				return b
+			}
+			return a
+		case aOK:
+			return a
+		case bOK:
+			return b
+		}
+	}
+
+	for _, b := range s.backends {
+		if isAvailable(b) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *p2cServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+}
+
+func (s *p2cServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *p2cServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
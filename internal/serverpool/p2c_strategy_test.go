@@ -0,0 +1,100 @@
+package serverpool
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestP2CServerPool(t *testing.T) {
+	pool, err := NewServerPool(PowerOfTwoChoices, WithRand(rand.New(rand.NewPCG(1, 2))))
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	// Test empty pool
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	busy := newWeightedMockBackend("http://localhost:8081", 1)
+	idle := newWeightedMockBackend("http://localhost:8082", 1)
+	pool.AddBackend(busy)
+	pool.AddBackend(idle)
+	busy.activeConnections = 5
+
+	// With two peers both are always sampled, so the idle one always wins
+	for i := 0; i < 10; i++ {
+		if got := pool.GetNextValidPeer(); got != idle {
+			t.Fatalf("GetNextValidPeer() = %v, want the least loaded backend", got.GetURL())
+		}
+	}
+
+	idle.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != busy {
+		t.Errorf("GetNextValidPeer() = %v, want the only alive backend", got)
+	}
+
+	busy.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestP2CServerPool_Balance(t *testing.T) {
+	pool, err := NewServerPool(PowerOfTwoChoices, WithRand(rand.New(rand.NewPCG(3, 4))))
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	backends := make([]*mockBackend, 20)
+	for i := range backends {
+		backends[i] = newWeightedMockBackend(fmt.Sprintf("http://10.0.0.%d:8080", i+1), 1)
+		pool.AddBackend(backends[i])
+	}
+
+	// Requests never complete, so connections pile up; P2C keeps the spread tight
+	for i := 0; i < 2000; i++ {
+		pool.GetNextValidPeer().Serve(nil, nil)
+	}
+
+	least, most := backends[0].activeConnections, backends[0].activeConnections
+	for _, b := range backends {
+		least = min(least, b.activeConnections)
+		most = max(most, b.activeConnections)
+	}
+	if most-least > 10 {
+		t.Errorf("connection spread = %d..%d, want within 10", least, most)
+	}
+
+	// Only one peer left alive is always found
+	for _, b := range backends[1:] {
+		b.SetAlive(false)
+	}
+	for i := 0; i < 10; i++ {
+		if got := pool.GetNextValidPeer(); got != backends[0] {
+			t.Fatalf("GetNextValidPeer() = %v, want the only alive backend", got)
+		}
+	}
+}
+
+func BenchmarkP2C(b *testing.B) {
+	pool, _ := NewServerPool(PowerOfTwoChoices)
+	lc, _ := NewServerPool(LeastConnected)
+	for i := 0; i < 500; i++ {
+		u := fmt.Sprintf("http://10.0.%d.%d:8080", i/256, i%256)
+		pool.AddBackend(newWeightedMockBackend(u, 1))
+		lc.AddBackend(newWeightedMockBackend(u, 1))
+	}
+
+	b.Run("p2c", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pool.GetNextValidPeer()
+		}
+	})
+	b.Run("least-connected", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			lc.GetNextValidPeer()
+		}
+	})
+}
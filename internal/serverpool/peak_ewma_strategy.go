@@ -0,0 +1,54 @@
+package serverpool
+
+import (
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// ewmaServerPool routes to the peer with the lowest peak EWMA latency
+// multiplied by its in-flight requests, so a backend that slows down while
+// staying alive sheds traffic to faster ones.
+type ewmaServerPool struct {
+	backends []backend.Backend
+	mux      sync.RWMutex
+}
+
+// ewmaScore is the expected wait on b. Unmeasured backends count as 1ns so
+// they are tried first and new ones are still spread by in-flight requests.
+func ewmaScore(b backend.Backend) float64 {
+	return float64(b.GetLatency()+1) * float64(b.GetActiveConnections()+1)
+}
+
+func (s *ewmaServerPool) GetNextValidPeer() backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var peer backend.Backend
+	var best float64
+	for _, b := range s.backends {
+		if !isAvailable(b) {
+			continue
+		}
+
+		if score := ewmaScore(b); peer == nil || score < best {
+			peer, best = b, score
+		}
+	}
+	return peer
+}
+
+func (s *ewmaServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+}
+
+func (s *ewmaServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *ewmaServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
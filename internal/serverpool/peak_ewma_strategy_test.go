@@ -0,0 +1,53 @@
+package serverpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeakEWMAServerPool(t *testing.T) {
+	pool, err := NewServerPool(PeakEWMA)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	// Test empty pool
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	fast := newWeightedMockBackend("http://localhost:8081", 1)
+	slow := newWeightedMockBackend("http://localhost:8082", 1)
+	pool.AddBackend(slow)
+	pool.AddBackend(fast)
+	fast.latency = 10 * time.Millisecond
+	slow.latency = 100 * time.Millisecond
+
+	if got := pool.GetNextValidPeer(); got != fast {
+		t.Errorf("GetNextValidPeer() = %v, want the faster backend", got.GetURL())
+	}
+
+	// In-flight requests multiply the expected wait: 10ms × 11 > 100ms × 1
+	fast.activeConnections = 10
+	if got := pool.GetNextValidPeer(); got != slow {
+		t.Errorf("GetNextValidPeer() = %v, want the idle slower backend", got.GetURL())
+	}
+
+	// Unmeasured backends are tried before measured ones
+	fresh := newWeightedMockBackend("http://localhost:8083", 1)
+	pool.AddBackend(fresh)
+	if got := pool.GetNextValidPeer(); got != fresh {
+		t.Errorf("GetNextValidPeer() = %v, want the unmeasured backend", got.GetURL())
+	}
+
+	fresh.SetAlive(false)
+	slow.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != fast {
+		t.Errorf("GetNextValidPeer() = %v, want the only alive backend", got.GetURL())
+	}
+
+	fast.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
@@ -0,0 +1,30 @@
+package serverpool
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// randSource draws random numbers for the randomised strategies. A seeded
+// generator makes picks reproducible in tests but must be locked; without one,
+// or on a nil source, the lock-free global generator is used.
+type randSource struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newRandSource(rnd *rand.Rand) *randSource {
+	return &randSource{rnd: rnd}
+}
+
+// IntN returns a random int in [0, n)
+func (s *randSource) IntN(n int) int {
+	if s == nil || s.rnd == nil {
+		return rand.IntN(n)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.rnd.IntN(n)
+}
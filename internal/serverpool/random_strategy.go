@@ -0,0 +1,115 @@
+package serverpool
+
+import (
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// randomSamples bounds how many random picks are tried before scanning the
+// pool from a random offset
+const randomSamples = 3
+
+// randomServerPool picks a uniformly random available peer. It keeps no
+// shared cursor, so concurrent picks do not contend on a lock.
+type randomServerPool struct {
+	backends []backend.Backend
+	rnd      *randSource
+	mux      sync.RWMutex
+}
+
+func (s *randomServerPool) GetNextValidPeer() backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	n := len(s.backends)
+	if n == 0 {
+		return nil
+	}
+
+	for attempt := 0; attempt < randomSamples; attempt++ {
+		if b := s.backends[s.rnd.IntN(n)]; isAvailable(b) {
+			return b
+		}
+	}
+
+	offset := s.rnd.IntN(n)
+	for i := 0; i < n; i++ {
+		if b := s.backends[(offset+i)%n]; isAvailable(b) {
+			return b
+		}
+	}
+	return nil
+}
+
+func (s *randomServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+}
+
+func (s *randomServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *randomServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
+
+// weightedRandomServerPool picks an available peer with probability
+// proportional to its weight. Peers in slow start count with their reduced
+// effective weight.
+type weightedRandomServerPool struct {
+	backends []backend.Backend
+	rnd      *randSource
+	mux      sync.RWMutex
+}
+
+func (s *weightedRandomServerPool) GetNextValidPeer() backend.Backend {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	total := 0
+	for _, b := range s.backends {
+		if isAvailable(b) {
+			total += effectiveWeight(b)
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	var last backend.Backend
+	pick := s.rnd.IntN(total)
+	for _, b := range s.backends {
+		if !isAvailable(b) {
+			continue
+		}
+
+		pick -= effectiveWeight(b)
+		if pick < 0 {
+			return b
+		}
+		last = b
+	}
+
+	// Availability or weights changed between the two passes
+	return last
+}
+
+func (s *weightedRandomServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+}
+
+func (s *weightedRandomServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *weightedRandomServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
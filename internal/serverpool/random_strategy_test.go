@@ -0,0 +1,132 @@
+package serverpool
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func newSeededPool(t *testing.T, strategy LBStrategy, seed uint64) ServerPool {
+	t.Helper()
+
+	pool, err := NewServerPool(strategy, WithRand(rand.New(rand.NewPCG(seed, seed))))
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+	return pool
+}
+
+func TestRandomServerPool(t *testing.T) {
+	pool := newSeededPool(t, Random, 1)
+
+	// Test empty pool
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	c := newWeightedMockBackend("http://localhost:8083", 1)
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+	pool.AddBackend(c)
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 3000; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+	for _, mb := range []*mockBackend{a, b, c} {
+		if counts[mb] < 900 || counts[mb] > 1100 {
+			t.Errorf("backend %v picked %d times, want about 1000", mb.GetURL(), counts[mb])
+		}
+	}
+
+	a.SetAlive(false)
+	b.SetAlive(false)
+	for i := 0; i < 10; i++ {
+		if got := pool.GetNextValidPeer(); got != c {
+			t.Fatalf("GetNextValidPeer() = %v, want the only alive backend", got)
+		}
+	}
+
+	c.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestRandomServerPool_Deterministic(t *testing.T) {
+	for _, strategy := range []LBStrategy{Random, WeightedRandom} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			first := newSeededPool(t, strategy, 42)
+			second := newSeededPool(t, strategy, 42)
+			for _, u := range []string{"http://localhost:8081", "http://localhost:8082", "http://localhost:8083"} {
+				first.AddBackend(newWeightedMockBackend(u, 1))
+				second.AddBackend(newWeightedMockBackend(u, 1))
+			}
+
+			// The same seed yields the same sequence of picks
+			for i := 0; i < 50; i++ {
+				got, want := first.GetNextValidPeer().GetURL(), second.GetNextValidPeer().GetURL()
+				if got.String() != want.String() {
+					t.Fatalf("pick %d: %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedRandomServerPool(t *testing.T) {
+	pool := newSeededPool(t, WeightedRandom, 7)
+
+	// Test empty pool
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	light := newWeightedMockBackend("http://localhost:8081", 1)
+	heavy := newWeightedMockBackend("http://localhost:8082", 3)
+	pool.AddBackend(light)
+	pool.AddBackend(heavy)
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 4000; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+	if counts[heavy] < 2850 || counts[heavy] > 3150 {
+		t.Errorf("heavy backend picked %d of 4000 times, want about 3000", counts[heavy])
+	}
+
+	heavy.SetAlive(false)
+	for i := 0; i < 10; i++ {
+		if got := pool.GetNextValidPeer(); got != light {
+			t.Fatalf("GetNextValidPeer() = %v, want the only alive backend", got)
+		}
+	}
+
+	light.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+}
+
+func TestWeightedRandomServerPool_SlowStart(t *testing.T) {
+	pool := newSeededPool(t, WeightedRandom, 7)
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	b.slowStartFactor = 0.1
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+
+	// b counts with an effective weight of 10 against 100, like under
+	// weighted round-robin
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 4000; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+	if counts[b] < 280 || counts[b] > 450 {
+		t.Errorf("warming backend picked %d of 4000 times, want about 364", counts[b])
+	}
+}
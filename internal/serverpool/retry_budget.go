@@ -0,0 +1,65 @@
+package serverpool
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+const (
+	defaultRetryRatio      = 0.2
+	defaultRetryMinRetries = 3
+)
+
+// RetryBudget caps the retries in flight across a pool to a fraction of the
+// requests in flight, so that retrying cannot multiply the load on a pool
+// that is already failing. MinRetries are always allowed so that retries
+// still work at low traffic.
+type RetryBudget struct {
+	ratio      float64
+	minRetries int64
+	requests   atomic.Int64
+	retries    atomic.Int64
+}
+
+// NewRetryBudget creates a budget allowing ratio retries per request in
+// flight, and at least minRetries. Zero values keep the defaults of 0.2
+// and 3.
+func NewRetryBudget(ratio float64, minRetries int) *RetryBudget {
+	if ratio <= 0 {
+		ratio = defaultRetryRatio
+	}
+	if minRetries <= 0 {
+		minRetries = defaultRetryMinRetries
+	}
+	return &RetryBudget{ratio: ratio, minRetries: int64(minRetries)}
+}
+
+// Begin counts a request towards the budget until End is called
+func (b *RetryBudget) Begin() {
+	b.requests.Add(1)
+}
+
+// End stops counting a request started with Begin
+func (b *RetryBudget) End() {
+	b.requests.Add(-1)
+}
+
+// Acquire reserves a retry, failing when the budget is spent. A successful
+// Acquire must be followed by Release once the retry completes.
+func (b *RetryBudget) Acquire() bool {
+	limit := max(b.minRetries, int64(math.Floor(b.ratio*float64(b.requests.Load()))))
+	for {
+		current := b.retries.Load()
+		if current >= limit {
+			return false
+		}
+		if b.retries.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// Release returns a retry reserved with Acquire
+func (b *RetryBudget) Release() {
+	b.retries.Add(-1)
+}
@@ -0,0 +1,40 @@
+package serverpool
+
+import "testing"
+
+func TestRetryBudget(t *testing.T) {
+	b := NewRetryBudget(0.5, 1)
+
+	// MinRetries are allowed without any traffic
+	if !b.Acquire() {
+		t.Fatal("Acquire() = false within the minimum, want true")
+	}
+	if b.Acquire() {
+		t.Fatal("Acquire() = true beyond the minimum with no requests, want false")
+	}
+
+	// Beyond that retries scale with the requests in flight
+	for i := 0; i < 4; i++ {
+		b.Begin()
+	}
+	if !b.Acquire() {
+		t.Fatal("Acquire() = false with 4 requests in flight, want true")
+	}
+	if b.Acquire() {
+		t.Fatal("Acquire() = true with 2 of 2 retries in flight, want false")
+	}
+
+	// Released retries and finished requests return to the budget
+	b.Release()
+	if !b.Acquire() {
+		t.Fatal("Acquire() = false after a release, want true")
+	}
+	b.Release()
+	b.Release()
+	for i := 0; i < 4; i++ {
+		b.End()
+	}
+	if !b.Acquire() || b.Acquire() {
+		t.Error("budget did not return to its minimum after requests ended")
+	}
+}
@@ -8,25 +8,55 @@ import (
 
 type roundRobinServerPool struct {
 	backends []backend.Backend
-	mux      sync.RWMutex
-	current  int
+	// credits accumulate the slow start shares of peers, indexed like
+	// backends
+	credits []int
+	mux     sync.RWMutex
+	current int
 }
 
 func (s *roundRobinServerPool) Rotate() backend.Backend {
 	s.mux.Lock()
-	s.current = (s.current + 1) % s.GetServerPoolSize()
-	s.mux.Unlock()
+	defer s.mux.Unlock()
+
+	s.current = (s.current + 1) % len(s.backends)
 	return s.backends[s.current]
 }
 
+// GetNextValidPeer returns the next available peer in turn. A peer in slow
+// start earns credit on each of its turns in proportion to its slow start
+// factor and is only picked once it has saved up a whole turn, so it ramps
+// up like it would under weighted round-robin. When every available peer is
+// still short of a turn, the one with the most credit is picked.
 func (s *roundRobinServerPool) GetNextValidPeer() backend.Backend {
-	for i := 0; i < s.GetServerPoolSize(); i++ {
-		nextPeer := s.Rotate()
-		if nextPeer.IsAlive() {
-			return nextPeer
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	n := len(s.backends)
+	warming := -1
+	for i := 0; i < n; i++ {
+		s.current = (s.current + 1) % n
+		peer := s.backends[s.current]
+		if !isAvailable(peer) {
+			continue
+		}
+
+		// Plain round-robin gives every peer a weight of one
+		s.credits[s.current] += max(1, int(slowStartScale*peer.GetSlowStartFactor()))
+		if s.credits[s.current] >= slowStartScale {
+			s.credits[s.current] -= slowStartScale
+			return peer
 		}
+		if warming < 0 || s.credits[s.current] > s.credits[warming] {
+			warming = s.current
+		}
+	}
+
+	if warming < 0 {
+		return nil
 	}
-	return nil
+	s.credits[warming] = max(0, s.credits[warming]-slowStartScale)
+	return s.backends[warming]
 }
 
 func (s *roundRobinServerPool) GetBackends() []backend.Backend {
@@ -34,7 +64,11 @@ func (s *roundRobinServerPool) GetBackends() []backend.Backend {
 }
 
 func (s *roundRobinServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
 	s.backends = append(s.backends, b)
+	s.credits = append(s.credits, 0)
 }
 
 func (s *roundRobinServerPool) GetServerPoolSize() int {
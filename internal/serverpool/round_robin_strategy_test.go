@@ -59,3 +59,32 @@ func TestRoundRobinServerPool(t *testing.T) {
 		t.Errorf("GetBackends() returned %v backends, want %v", len(backends), len(urls))
 	}
 }
+
+func TestRoundRobinServerPool_SlowStart(t *testing.T) {
+	pool := &roundRobinServerPool{}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	b.slowStartFactor = 0.1
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 1000; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+
+	// b takes one turn in ten, the share weighted round-robin gives it with
+	// weights of 100 and 10
+	if counts[b] < 88 || counts[b] > 94 {
+		t.Errorf("warming backend received %d of 1000 requests, want about 91", counts[b])
+	}
+
+	// A warming peer is still used when it is the only one available
+	a.SetAlive(false)
+	for i := 0; i < 10; i++ {
+		if got := pool.GetNextValidPeer(); got != b {
+			t.Fatalf("GetNextValidPeer() = %v, want %v", got, b.GetURL())
+		}
+	}
+}
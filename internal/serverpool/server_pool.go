@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	"time"
+	"net/http"
+	"slices"
 
 	"github.com/darshan-rambhia/eisodos/internal/backend"
 )
@@ -16,25 +17,107 @@ type ServerPool interface {
 	GetServerPoolSize() int
 }
 
-func HealthCheck(ctx context.Context, s ServerPool) {
-	aliveChannel := make(chan bool, 1)
+// RequestAwareServerPool is implemented by strategies that pick a peer from
+// attributes of the incoming request
+type RequestAwareServerPool interface {
+	ServerPool
+	GetNextValidPeerForRequest(*http.Request) backend.Backend
+}
+
+// NextValidPeer picks a peer for r, letting request aware strategies inspect it
+func NextValidPeer(s ServerPool, r *http.Request) backend.Backend {
+	if ra, ok := s.(RequestAwareServerPool); ok {
+		return ra.GetNextValidPeerForRequest(r)
+	}
+	return s.GetNextValidPeer()
+}
+
+// NextUntriedPeer picks a peer to retry or hedge r on, avoiding the peers in
+// tried. The strategy is asked first so retries keep its balancing; when it
+// keeps choosing tried peers, as hash based strategies do, any available
+// untried peer is used instead.
+func NextUntriedPeer(s ServerPool, r *http.Request, tried []backend.Backend) backend.Backend {
+	for i := 0; i < 2; i++ {
+		peer := NextValidPeer(s, r)
+		if peer == nil {
+			return nil
+		}
+		if !slices.Contains(tried, peer) {
+			return peer
+		}
+	}
+
+	for _, b := range s.GetBackends() {
+		if isAvailable(b) && !slices.Contains(tried, b) {
+			return b
+		}
+	}
+	return nil
+}
+
+// healthObserver is implemented by pools that cache state derived from
+// backend health and must refresh it when that health changes
+type healthObserver interface {
+	healthChanged()
+}
+
+// isHealthy reports whether a peer passes its health checks and has not
+// been ejected by outlier detection
+func isHealthy(b backend.Backend) bool {
+	return b.IsAlive() && !b.IsEjected()
+}
+
+// isAvailable reports whether a peer can take a new request right now
+func isAvailable(b backend.Backend) bool {
+	return isHealthy(b) && !b.IsSaturated() && !b.IsCircuitOpen()
+}
 
+// slowStartScale multiplies weights so that the fractions of slow start
+// survive integer arithmetic
+const slowStartScale = 100
+
+// effectiveWeight returns the weight of b, scaled by slowStartScale and
+// reduced while b is in slow start
+func effectiveWeight(b backend.Backend) int {
+	return max(1, int(float64(b.GetWeight()*slowStartScale)*b.GetSlowStartFactor()))
+}
+
+// IsSaturated reports whether the pool has healthy peers but all of them are
+// at their connection limit, i.e. waiting for a slot may succeed
+func IsSaturated(s ServerPool) bool {
+	saturated := false
 	for _, b := range s.GetBackends() {
-		b := b
-		requestCtx, stop := context.WithTimeout(ctx, 10*time.Second)
-		defer stop()
+		if !isHealthy(b) {
+			continue
+		}
+		if !b.IsSaturated() {
+			return false
+		}
+		saturated = true
+	}
+	return saturated
+}
+
+// HealthCheck probes every backend once, concurrently, and applies the
+// results directly without the thresholds of HealthMonitor
+func HealthCheck(ctx context.Context, s ServerPool) {
+	backends := s.GetBackends()
+
+	results := probeConcurrently(ctx, backends)
+	if ctx.Err() != nil {
+		slog.Info("Gracefully shutting down health check")
+		return
+	}
+
+	changed := false
+	for i, b := range backends {
+		alive := results[i]
+		changed = changed || b.IsAlive() != alive
+		b.SetAlive(alive)
+
 		status := "up"
-		go backend.IsBackendAlive(requestCtx, aliveChannel, b.GetURL())
-
-		select {
-		case <-ctx.Done():
-			slog.Info("Gracefully shutting down health check")
-			return
-		case alive := <-aliveChannel:
-			b.SetAlive(alive)
-			if !alive {
-				status = "down"
-			}
+		if !alive {
+			status = "down"
 		}
 		slog.Debug(
 			"URL Status",
@@ -42,9 +125,19 @@ func HealthCheck(ctx context.Context, s ServerPool) {
 			"status", status,
 		)
 	}
+
+	if o, ok := s.(healthObserver); ok && changed {
+		o.healthChanged()
+	}
 }
 
-func NewServerPool(strategy LBStrategy) (ServerPool, error) {
+func NewServerPool(strategy LBStrategy, opts ...Option) (ServerPool, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.hashKey.proxies = o.proxies
+
 	switch strategy {
 	case RoundRobin:
 		return &roundRobinServerPool{
@@ -55,6 +148,58 @@ func NewServerPool(strategy LBStrategy) (ServerPool, error) {
 		return &lcServerPool{
 			backends: make([]backend.Backend, 0),
 		}, nil
+	case WeightedRoundRobin:
+		return &wrrServerPool{
+			backends:       make([]backend.Backend, 0),
+			currentWeights: make([]int, 0),
+		}, nil
+	case ConsistentHash:
+		return &chServerPool{
+			backends: make([]backend.Backend, 0),
+			ring:     newHashRing(o.virtualNodes),
+			key:      o.hashKey,
+		}, nil
+	case BoundedConsistentHash:
+		return &bchServerPool{
+			chServerPool: &chServerPool{
+				backends: make([]backend.Backend, 0),
+				ring:     newHashRing(o.virtualNodes),
+				key:      o.hashKey,
+			},
+			epsilon: o.loadEpsilon,
+		}, nil
+	case Maglev:
+		return &maglevServerPool{
+			backends: make([]backend.Backend, 0),
+			key:      o.hashKey,
+		}, nil
+	case PowerOfTwoChoices:
+		return &p2cServerPool{
+			backends: make([]backend.Backend, 0),
+			rnd:      newRandSource(o.rnd),
+		}, nil
+	case PeakEWMA:
+		return &ewmaServerPool{
+			backends: make([]backend.Backend, 0),
+		}, nil
+	case Random:
+		return &randomServerPool{
+			backends: make([]backend.Backend, 0),
+			rnd:      newRandSource(o.rnd),
+		}, nil
+	case WeightedRandom:
+		return &weightedRandomServerPool{
+			backends: make([]backend.Backend, 0),
+			rnd:      newRandSource(o.rnd),
+		}, nil
+	case IPHash:
+		return &ipHashServerPool{
+			chServerPool: &chServerPool{
+				backends: make([]backend.Backend, 0),
+				ring:     newHashRing(o.virtualNodes),
+				key:      HashKey{Source: HashByClientIP, proxies: o.proxies},
+			},
+		}, nil
 	default:
 		return nil, errors.New("invalid strategy")
 	}
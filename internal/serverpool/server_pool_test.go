@@ -2,10 +2,16 @@ package serverpool
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
+	"slices"
 	"testing"
 	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
 )
 
 func TestNewServerPool(t *testing.T) {
@@ -24,6 +30,51 @@ func TestNewServerPool(t *testing.T) {
 			strategy: LeastConnected,
 			wantErr:  false,
 		},
+		{
+			name:     "weighted round-robin strategy",
+			strategy: WeightedRoundRobin,
+			wantErr:  false,
+		},
+		{
+			name:     "consistent hash strategy",
+			strategy: ConsistentHash,
+			wantErr:  false,
+		},
+		{
+			name:     "bounded consistent hash strategy",
+			strategy: BoundedConsistentHash,
+			wantErr:  false,
+		},
+		{
+			name:     "maglev strategy",
+			strategy: Maglev,
+			wantErr:  false,
+		},
+		{
+			name:     "power of two choices strategy",
+			strategy: PowerOfTwoChoices,
+			wantErr:  false,
+		},
+		{
+			name:     "peak EWMA strategy",
+			strategy: PeakEWMA,
+			wantErr:  false,
+		},
+		{
+			name:     "random strategy",
+			strategy: Random,
+			wantErr:  false,
+		},
+		{
+			name:     "weighted random strategy",
+			strategy: WeightedRandom,
+			wantErr:  false,
+		},
+		{
+			name:     "ip hash strategy",
+			strategy: IPHash,
+			wantErr:  false,
+		},
 		{
 			name:     "invalid strategy",
 			strategy: LBStrategy(999), // Invalid strategy
@@ -83,3 +134,214 @@ func TestHealthCheck(t *testing.T) {
 		t.Error("HealthCheck did not stop after context cancellation")
 	}
 }
+
+func TestIsSaturated(t *testing.T) {
+	sp, err := NewServerPool(RoundRobin)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	// An empty pool is not saturated: waiting would never help
+	if IsSaturated(sp) {
+		t.Error("IsSaturated() = true for empty pool, want false")
+	}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	a.maxConns, b.maxConns = 1, 1
+	sp.AddBackend(a)
+	sp.AddBackend(b)
+
+	a.Serve(nil, nil)
+	if IsSaturated(sp) {
+		t.Error("IsSaturated() = true with a free backend, want false")
+	}
+	if got := sp.GetNextValidPeer(); got != b {
+		t.Errorf("GetNextValidPeer() = %v, want the unsaturated backend", got.GetURL())
+	}
+
+	b.Serve(nil, nil)
+	if !IsSaturated(sp) {
+		t.Error("IsSaturated() = false with every backend at maxConns, want true")
+	}
+	if got := sp.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got.GetURL())
+	}
+
+	// Dead backends do not count towards saturation
+	b.SetAlive(false)
+	a.SetAlive(false)
+	if IsSaturated(sp) {
+		t.Error("IsSaturated() = true with no alive backends, want false")
+	}
+}
+
+func TestNextValidPeerSkipsOpenCircuits(t *testing.T) {
+	for _, strategy := range []LBStrategy{RoundRobin, LeastConnected, WeightedRoundRobin, PowerOfTwoChoices, PeakEWMA} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			sp, err := NewServerPool(strategy)
+			if err != nil {
+				t.Fatalf("Failed to create server pool: %v", err)
+			}
+
+			a := newWeightedMockBackend("http://localhost:8081", 1)
+			b := newWeightedMockBackend("http://localhost:8082", 1)
+			a.circuitState = backend.CircuitOpen
+			sp.AddBackend(a)
+			sp.AddBackend(b)
+
+			for i := 0; i < 4; i++ {
+				if got := sp.GetNextValidPeer(); got != b {
+					t.Fatalf("GetNextValidPeer() = %v, want the backend with a closed circuit", got)
+				}
+			}
+
+			// A half-open circuit with free trial slots takes requests
+			a.circuitState = backend.CircuitHalfOpen
+			b.SetAlive(false)
+			if got := sp.GetNextValidPeer(); got != a {
+				t.Errorf("GetNextValidPeer() = %v, want the half-open backend", got)
+			}
+		})
+	}
+}
+
+func TestHealthCheckUsesBackendHealthChecker(t *testing.T) {
+	sp, err := NewServerPool(RoundRobin)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	// The server accepts connections but fails its health endpoint
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	tcp := newWeightedMockBackend(server.URL, 1)
+	httpChecked := newWeightedMockBackend(server.URL, 1)
+	httpChecked.healthChecker = &backend.HTTPHealthChecker{Path: "/healthz"}
+	sp.AddBackend(tcp)
+	sp.AddBackend(httpChecked)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	HealthCheck(ctx, sp)
+
+	if !tcp.IsAlive() {
+		t.Error("TCP checked backend should be alive")
+	}
+	if httpChecked.IsAlive() {
+		t.Error("HTTP checked backend returning 500 should be down")
+	}
+}
+
+func TestHealthCheckProbesConcurrently(t *testing.T) {
+	sp, err := NewServerPool(RoundRobin)
+	if err != nil {
+		t.Fatalf("Failed to create server pool: %v", err)
+	}
+
+	// Every backend black-holes its health check
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	const probeTimeout = 100 * time.Millisecond
+	backends := make([]*mockBackend, 20)
+	for i := range backends {
+		backends[i] = newWeightedMockBackend(server.URL, 1)
+		backends[i].healthChecker = &backend.HTTPHealthChecker{Timeout: probeTimeout}
+		sp.AddBackend(backends[i])
+	}
+
+	start := time.Now()
+	HealthCheck(context.Background(), sp)
+
+	// Sequential probes would take 20 × 100ms
+	if elapsed := time.Since(start); elapsed > 5*probeTimeout {
+		t.Errorf("HealthCheck() took %v, want about one probe timeout", elapsed)
+	}
+	for i, b := range backends {
+		if b.IsAlive() {
+			t.Errorf("backend %d should be down after timing out", i)
+		}
+	}
+}
+
+func TestNextUntriedPeer(t *testing.T) {
+	for _, strategy := range []LBStrategy{RoundRobin, ConsistentHash, Maglev, IPHash} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			sp, err := NewServerPool(strategy)
+			if err != nil {
+				t.Fatalf("Failed to create server pool: %v", err)
+			}
+
+			backends := []*mockBackend{
+				newWeightedMockBackend("http://localhost:8081", 1),
+				newWeightedMockBackend("http://localhost:8082", 1),
+				newWeightedMockBackend("http://localhost:8083", 1),
+			}
+			for _, b := range backends {
+				sp.AddBackend(b)
+			}
+			if o, ok := sp.(healthObserver); ok {
+				o.healthChanged()
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			tried := []backend.Backend{}
+			for range backends {
+				peer := NextUntriedPeer(sp, r, tried)
+				if peer == nil || slices.Contains(tried, peer) {
+					t.Fatalf("NextUntriedPeer() = %v after trying %d peers, want an untried peer", peer, len(tried))
+				}
+				tried = append(tried, peer)
+			}
+
+			if peer := NextUntriedPeer(sp, r, tried); peer != nil {
+				t.Errorf("NextUntriedPeer() = %v with every peer tried, want nil", peer.GetURL())
+			}
+		})
+	}
+}
+
+func TestAddBackendWhileServing(t *testing.T) {
+	for strategy, name := range strategyNames {
+		t.Run(name, func(t *testing.T) {
+			pool, err := NewServerPool(strategy)
+			if err != nil {
+				t.Fatalf("Failed to create server pool: %v", err)
+			}
+			pool.AddBackend(newWeightedMockBackend("http://10.0.0.1:8080", 1))
+
+			// Picks must be safe while backends are added, e.g. by
+			// LoadBalancer.AddBackend; run with -race
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 2; i <= 50; i++ {
+					pool.AddBackend(newWeightedMockBackend(fmt.Sprintf("http://10.0.0.%d:8080", i), 1))
+				}
+			}()
+
+			req := httptest.NewRequest("GET", "/", nil)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if NextValidPeer(pool, req) == nil {
+					t.Fatal("GetNextValidPeerForRequest() = nil, want non-nil")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package serverpool
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// DefaultStickyCookieName is used when StickyOptions leaves CookieName empty
+const DefaultStickyCookieName = "eisodos_backend"
+
+// PeerBinder is implemented by pools that record the chosen peer on the
+// response, such as session affinity cookies
+type PeerBinder interface {
+	BindPeer(http.ResponseWriter, *http.Request, backend.Backend)
+}
+
+// StickyOptions configures cookie based session persistence. A zero TTL
+// issues session cookies that never expire server side.
+type StickyOptions struct {
+	CookieName string
+	TTL        time.Duration
+	SameSite   http.SameSite
+	Secure     bool
+	Key        []byte
+}
+
+// ParseSameSite converts the configuration form of a SameSite attribute
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return http.SameSiteDefaultMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return 0, fmt.Errorf("unknown SameSite mode %q", s)
+	}
+}
+
+// stickyServerPool pins clients to the backend named in a signed cookie and
+// falls back to the wrapped strategy when the cookie is missing, invalid or
+// names a backend that cannot take the request
+type stickyServerPool struct {
+	ServerPool
+	opts StickyOptions
+	now  func() time.Time
+}
+
+// NewStickyServerPool layers cookie based session persistence over pool
+func NewStickyServerPool(pool ServerPool, opts StickyOptions) ServerPool {
+	if opts.CookieName == "" {
+		opts.CookieName = DefaultStickyCookieName
+	}
+
+	return &stickyServerPool{
+		ServerPool: pool,
+		opts:       opts,
+		now:        time.Now,
+	}
+}
+
+func (s *stickyServerPool) GetNextValidPeerForRequest(r *http.Request) backend.Backend {
+	if b := s.pinned(r); b != nil && isAvailable(b) {
+		return b
+	}
+	return NextValidPeer(s.ServerPool, r)
+}
+
+// BindPeer issues a cookie for peer unless the request already carries one
+func (s *stickyServerPool) BindPeer(w http.ResponseWriter, r *http.Request, peer backend.Backend) {
+	if s.pinned(r) == peer {
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     s.opts.CookieName,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.opts.Secure,
+		SameSite: s.opts.SameSite,
+	}
+
+	var expires int64
+	if s.opts.TTL > 0 {
+		expiry := s.now().Add(s.opts.TTL)
+		expires = expiry.Unix()
+		cookie.Expires = expiry
+		cookie.MaxAge = int(s.opts.TTL.Seconds())
+	}
+
+	cookie.Value = s.sign(backendID(peer), expires)
+	http.SetCookie(w, cookie)
+}
+
+// healthChanged forwards health notifications to the wrapped pool
+func (s *stickyServerPool) healthChanged() {
+	if o, ok := s.ServerPool.(healthObserver); ok {
+		o.healthChanged()
+	}
+}
+
+// pinned returns the backend named by a valid cookie on r, if any
+func (s *stickyServerPool) pinned(r *http.Request) backend.Backend {
+	c, err := r.Cookie(s.opts.CookieName)
+	if err != nil {
+		return nil
+	}
+
+	id, ok := s.verify(c.Value)
+	if !ok {
+		return nil
+	}
+
+	for _, b := range s.GetBackends() {
+		if backendID(b) == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// sign encodes "<id>.<expiry>.<mac>"; an expiry of zero never expires
+func (s *stickyServerPool) sign(id string, expires int64) string {
+	payload := id + "." + strconv.FormatInt(expires, 10)
+	return payload + "." + s.mac(payload)
+}
+
+func (s *stickyServerPool) verify(value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false
+	}
+
+	payload, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(s.mac(payload))) {
+		return "", false
+	}
+
+	id, rawExpiry, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(rawExpiry, 10, 64)
+	if err != nil || (expires != 0 && s.now().Unix() >= expires) {
+		return "", false
+	}
+	return id, true
+}
+
+func (s *stickyServerPool) mac(payload string) string {
+	m := hmac.New(sha256.New, s.opts.Key)
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// backendID is an opaque, stable identifier that does not leak backend
+// addresses to clients
+func backendID(b backend.Backend) string {
+	return strconv.FormatUint(HashString(b.GetURL().String()), 16)
+}
@@ -0,0 +1,167 @@
+package serverpool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestStickyPool(t *testing.T, ttl time.Duration) (*stickyServerPool, []*mockBackend) {
+	t.Helper()
+
+	inner, backends := newTestHashPool(t, RoundRobin, 3)
+	pool := NewStickyServerPool(inner, StickyOptions{
+		TTL:      ttl,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   true,
+		Key:      []byte("0123456789abcdef"),
+	}).(*stickyServerPool)
+	return pool, backends
+}
+
+// stickyRoundTrip picks a peer for req, binds it and returns the issued cookie
+func stickyRoundTrip(pool *stickyServerPool, req *http.Request) (string, *http.Cookie) {
+	rec := httptest.NewRecorder()
+	peer := NextValidPeer(pool, req)
+	pool.BindPeer(rec, req, peer)
+
+	var cookie *http.Cookie
+	if cookies := rec.Result().Cookies(); len(cookies) > 0 {
+		cookie = cookies[0]
+	}
+	return peer.GetURL().String(), cookie
+}
+
+func TestStickyServerPool(t *testing.T) {
+	pool, backends := newTestStickyPool(t, time.Hour)
+
+	// The first request is balanced by the wrapped strategy and gets a cookie
+	first, cookie := stickyRoundTrip(pool, httptest.NewRequest("GET", "/", nil))
+	if cookie == nil {
+		t.Fatal("BindPeer() did not set a cookie")
+	}
+	if cookie.Name != DefaultStickyCookieName || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+	if strings.Contains(cookie.Value, "localhost") {
+		t.Errorf("cookie value %q leaks the backend address", cookie.Value)
+	}
+
+	// Requests with the cookie stick to the same backend without a new cookie
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(cookie)
+
+		got, reissued := stickyRoundTrip(pool, req)
+		if got != first {
+			t.Fatalf("request %d went to %v, want %v", i, got, first)
+		}
+		if reissued != nil {
+			t.Fatalf("request %d re-issued the cookie", i)
+		}
+	}
+
+	// When the pinned backend dies the request falls back and the cookie is re-issued
+	for _, b := range backends {
+		if b.GetURL().String() == first {
+			b.SetAlive(false)
+		}
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+
+	got, reissued := stickyRoundTrip(pool, req)
+	if got == first {
+		t.Errorf("request went to dead backend %v", got)
+	}
+	if reissued == nil || reissued.Value == cookie.Value {
+		t.Error("cookie was not re-issued for the new backend")
+	}
+}
+
+func TestStickyServerPool_RejectsInvalidCookies(t *testing.T) {
+	pool, _ := newTestStickyPool(t, time.Hour)
+	_, cookie := stickyRoundTrip(pool, httptest.NewRequest("GET", "/", nil))
+
+	tamperedID := "0" + cookie.Value[1:]
+	if tamperedID == cookie.Value {
+		tamperedID = "1" + cookie.Value[1:]
+	}
+
+	tamperedSig := cookie.Value[:len(cookie.Value)-1] + "A"
+	if tamperedSig == cookie.Value {
+		tamperedSig = cookie.Value[:len(cookie.Value)-1] + "B"
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "garbage", value: "not-a-cookie"},
+		{name: "tampered backend", value: tamperedID},
+		{name: "tampered signature", value: tamperedSig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.AddCookie(&http.Cookie{Name: DefaultStickyCookieName, Value: tt.value})
+
+			if got := pool.pinned(req); got != nil {
+				t.Errorf("pinned() = %v, want nil", got.GetURL())
+			}
+		})
+	}
+
+	// Expired cookies are ignored
+	pool.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	if got := pool.pinned(req); got != nil {
+		t.Errorf("pinned() = %v for an expired cookie, want nil", got.GetURL())
+	}
+}
+
+func TestStickyServerPool_SessionCookie(t *testing.T) {
+	pool, _ := newTestStickyPool(t, 0)
+	_, cookie := stickyRoundTrip(pool, httptest.NewRequest("GET", "/", nil))
+
+	if cookie.MaxAge != 0 || !cookie.Expires.IsZero() {
+		t.Errorf("cookie with zero TTL has MaxAge=%d Expires=%v, want a session cookie", cookie.MaxAge, cookie.Expires)
+	}
+
+	pool.now = func() time.Time { return time.Now().Add(24 * 365 * time.Hour) }
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	if got := pool.pinned(req); got == nil {
+		t.Error("pinned() = nil for a session cookie, want its backend")
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    http.SameSite
+		wantErr bool
+	}{
+		{in: "", want: http.SameSiteDefaultMode},
+		{in: "Lax", want: http.SameSiteLaxMode},
+		{in: "strict", want: http.SameSiteStrictMode},
+		{in: "none", want: http.SameSiteNoneMode},
+		{in: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSameSite(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSameSite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSameSite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package serverpool
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"strconv"
+)
 
 // LBStrategy represents the type of load balancing strategy
 type LBStrategy int
@@ -8,17 +12,77 @@ type LBStrategy int
 const (
 	RoundRobin LBStrategy = iota
 	LeastConnected
+	WeightedRoundRobin
+	ConsistentHash
+	BoundedConsistentHash
+	Maglev
+	PowerOfTwoChoices
+	PeakEWMA
+	Random
+	WeightedRandom
+	IPHash
 )
 
+var strategyNames = map[LBStrategy]string{
+	RoundRobin:            "round-robin",
+	LeastConnected:        "least-connected",
+	WeightedRoundRobin:    "weighted-round-robin",
+	ConsistentHash:        "consistent-hash",
+	BoundedConsistentHash: "consistent-hash-bounded",
+	Maglev:                "maglev",
+	PowerOfTwoChoices:     "p2c",
+	PeakEWMA:              "peak-ewma",
+	Random:                "random",
+	WeightedRandom:        "weighted-random",
+	IPHash:                "ip-hash",
+}
+
+// StrategyNames returns the configuration names of all strategies in
+// declaration order
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategyNames))
+	for s := RoundRobin; s <= IPHash; s++ {
+		names = append(names, strategyNames[s])
+	}
+	return names
+}
+
+// String returns the configuration name of the strategy
+func (s LBStrategy) String() string {
+	if name, ok := strategyNames[s]; ok {
+		return name
+	}
+	return "LBStrategy(" + strconv.Itoa(int(s)) + ")"
+}
+
+// UnmarshalYAML accepts either a strategy name or its numeric value
+func (s *LBStrategy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var raw string
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	if n, err := strconv.Atoi(raw); err == nil {
+		*s = LBStrategy(n)
+		return nil
+	}
+
+	for strategy, name := range strategyNames {
+		if name == raw {
+			*s = strategy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown strategy %q", raw)
+}
+
 // ParseStrategy converts a string strategy to LBStrategy
 func ParseStrategy(strategy string) LBStrategy {
-	switch strategy {
-	case "round-robin":
-		return RoundRobin
-	case "least-connected":
-		return LeastConnected
-	default:
-		log.Printf("Unknown strategy %s, defaulting to round-robin", strategy)
-		return RoundRobin
+	for s, name := range strategyNames {
+		if name == strategy {
+			return s
+		}
 	}
+	log.Printf("Unknown strategy %s, defaulting to round-robin", strategy)
+	return RoundRobin
 }
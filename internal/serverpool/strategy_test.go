@@ -1,6 +1,10 @@
 package serverpool
 
-import "testing"
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
 
 func TestParseStrategy(t *testing.T) {
 	tests := []struct {
@@ -18,6 +22,51 @@ func TestParseStrategy(t *testing.T) {
 			strategy: "least-connected",
 			want:     LeastConnected,
 		},
+		{
+			name:     "weighted round-robin strategy",
+			strategy: "weighted-round-robin",
+			want:     WeightedRoundRobin,
+		},
+		{
+			name:     "consistent hash strategy",
+			strategy: "consistent-hash",
+			want:     ConsistentHash,
+		},
+		{
+			name:     "bounded consistent hash strategy",
+			strategy: "consistent-hash-bounded",
+			want:     BoundedConsistentHash,
+		},
+		{
+			name:     "maglev strategy",
+			strategy: "maglev",
+			want:     Maglev,
+		},
+		{
+			name:     "power of two choices strategy",
+			strategy: "p2c",
+			want:     PowerOfTwoChoices,
+		},
+		{
+			name:     "peak EWMA strategy",
+			strategy: "peak-ewma",
+			want:     PeakEWMA,
+		},
+		{
+			name:     "random strategy",
+			strategy: "random",
+			want:     Random,
+		},
+		{
+			name:     "weighted random strategy",
+			strategy: "weighted-random",
+			want:     WeightedRandom,
+		},
+		{
+			name:     "ip hash strategy",
+			strategy: "ip-hash",
+			want:     IPHash,
+		},
 		{
 			name:     "unknown strategy defaults to round-robin",
 			strategy: "unknown",
@@ -33,3 +82,58 @@ func TestParseStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestStrategyNames(t *testing.T) {
+	names := StrategyNames()
+	if len(names) != len(strategyNames) {
+		t.Fatalf("StrategyNames() has %d names, want %d", len(names), len(strategyNames))
+	}
+
+	// Every listed name is accepted by ParseStrategy
+	for i, name := range names {
+		if got := ParseStrategy(name); got != LBStrategy(i) {
+			t.Errorf("ParseStrategy(%q) = %v, want %v", name, got, LBStrategy(i))
+		}
+	}
+}
+
+func TestLBStrategyUnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    LBStrategy
+		wantErr bool
+	}{
+		{
+			name: "strategy by name",
+			yaml: "strategy: weighted-round-robin",
+			want: WeightedRoundRobin,
+		},
+		{
+			name: "strategy by number",
+			yaml: "strategy: 1",
+			want: LeastConnected,
+		},
+		{
+			name:    "unknown strategy name",
+			yaml:    "strategy: fastest",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg struct {
+				Strategy LBStrategy `yaml:"strategy"`
+			}
+
+			err := yaml.Unmarshal([]byte(tt.yaml), &cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.Strategy != tt.want {
+				t.Errorf("Unmarshal() strategy = %v, want %v", cfg.Strategy, tt.want)
+			}
+		})
+	}
+}
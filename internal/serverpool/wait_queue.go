@@ -0,0 +1,112 @@
+package serverpool
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// ErrQueueFull is returned when the wait queue has no room for another request
+var ErrQueueFull = errors.New("wait queue is full")
+
+// WaitQueue parks requests in FIFO order while every peer is saturated and
+// hands them a peer as soon as a connection slot is released
+type WaitQueue struct {
+	mu      sync.Mutex
+	waiters *list.List
+	size    int
+	timeout time.Duration
+}
+
+// NewWaitQueue creates a queue holding at most size requests, each for at most timeout
+func NewWaitQueue(size int, timeout time.Duration) *WaitQueue {
+	return &WaitQueue{
+		waiters: list.New(),
+		size:    size,
+		timeout: timeout,
+	}
+}
+
+// Wait blocks until next yields a peer, the queue timeout expires or ctx is
+// cancelled. A waiter that is woken but loses the freed slot to another
+// request keeps its place at the head of the queue.
+func (q *WaitQueue) Wait(ctx context.Context, next func() backend.Backend) (backend.Backend, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	q.mu.Lock()
+	if q.waiters.Len() >= q.size {
+		q.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	ready := make(chan struct{})
+	elem := q.waiters.PushBack(ready)
+	q.mu.Unlock()
+
+	// A slot may have been released between the caller's check and enqueueing
+	if peer := next(); peer != nil {
+		q.leave(elem, ready)
+		return peer, nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.leave(elem, ready)
+			return nil, ctx.Err()
+		case <-ready:
+			if peer := next(); peer != nil {
+				return peer, nil
+			}
+
+			q.mu.Lock()
+			ready = make(chan struct{})
+			elem = q.waiters.PushFront(ready)
+			q.mu.Unlock()
+		}
+	}
+}
+
+// Release wakes the request at the head of the queue, if any
+func (q *WaitQueue) Release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.releaseLocked()
+}
+
+// Len returns the number of parked requests
+func (q *WaitQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.waiters.Len()
+}
+
+func (q *WaitQueue) releaseLocked() {
+	front := q.waiters.Front()
+	if front == nil {
+		return
+	}
+
+	q.waiters.Remove(front)
+	close(front.Value.(chan struct{}))
+}
+
+// leave removes a waiter that gives up, passing on a wake-up it may have
+// received concurrently so the release is not lost
+func (q *WaitQueue) leave(elem *list.Element, ready chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	select {
+	case <-ready:
+		q.releaseLocked()
+	default:
+		q.waiters.Remove(elem)
+	}
+}
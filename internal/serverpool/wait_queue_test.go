@@ -0,0 +1,125 @@
+package serverpool
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func TestWaitQueue(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8081")
+	peer := newMockBackend(u, nil)
+
+	t.Run("returns immediately when a peer is free", func(t *testing.T) {
+		q := NewWaitQueue(1, time.Second)
+
+		got, err := q.Wait(context.Background(), func() backend.Backend { return peer })
+		if err != nil || got != peer {
+			t.Errorf("Wait() = %v, %v, want %v, nil", got, err, peer)
+		}
+		if q.Len() != 0 {
+			t.Errorf("Len() = %d, want 0", q.Len())
+		}
+	})
+
+	t.Run("times out when no slot is released", func(t *testing.T) {
+		q := NewWaitQueue(1, 50*time.Millisecond)
+
+		got, err := q.Wait(context.Background(), func() backend.Backend { return nil })
+		if !errors.Is(err, context.DeadlineExceeded) || got != nil {
+			t.Errorf("Wait() = %v, %v, want nil, %v", got, err, context.DeadlineExceeded)
+		}
+		if q.Len() != 0 {
+			t.Errorf("Len() = %d, want 0", q.Len())
+		}
+	})
+
+	t.Run("rejects requests beyond its size", func(t *testing.T) {
+		q := NewWaitQueue(1, time.Second)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() { _, _ = q.Wait(ctx, func() backend.Backend { return nil }) }()
+		waitForLen(t, q, 1)
+
+		if _, err := q.Wait(context.Background(), func() backend.Backend { return nil }); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("Wait() error = %v, want %v", err, ErrQueueFull)
+		}
+	})
+
+	t.Run("wakes waiters in FIFO order", func(t *testing.T) {
+		q := NewWaitQueue(3, time.Second)
+
+		var mu sync.Mutex
+		free := false
+		var order []int
+		next := func() backend.Backend {
+			mu.Lock()
+			defer mu.Unlock()
+			if !free {
+				return nil
+			}
+			free = false
+			return peer
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				if _, err := q.Wait(context.Background(), next); err != nil {
+					t.Errorf("Wait() error = %v", err)
+					return
+				}
+				mu.Lock()
+				order = append(order, id)
+				mu.Unlock()
+			}(i)
+			waitForLen(t, q, i+1)
+		}
+
+		for i := 0; i < 3; i++ {
+			mu.Lock()
+			free = true
+			mu.Unlock()
+			q.Release()
+
+			// Let the woken waiter take the slot before freeing another
+			deadline := time.Now().Add(time.Second)
+			for {
+				mu.Lock()
+				served := len(order)
+				mu.Unlock()
+				if served == i+1 || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		wg.Wait()
+
+		for i, id := range order {
+			if id != i {
+				t.Fatalf("wake-up order = %v, want [0 1 2]", order)
+			}
+		}
+	})
+}
+
+func waitForLen(t *testing.T, q *WaitQueue, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for q.Len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Len() = %d, want %d", q.Len(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,62 @@
+package serverpool
+
+import (
+	"sync"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+// wrrServerPool implements smooth weighted round-robin as used by nginx:
+// on every pick each available peer gains its weight, the peer with the highest
+// running total wins and is penalised by the sum of all weights. This yields
+// the configured ratio without sending consecutive bursts to heavy peers.
+// Peers in slow start count with their reduced effective weight.
+type wrrServerPool struct {
+	backends       []backend.Backend
+	currentWeights []int
+	mux            sync.Mutex
+}
+
+func (s *wrrServerPool) GetNextValidPeer() backend.Backend {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	selected := -1
+	total := 0
+	for i, b := range s.backends {
+		if !isAvailable(b) {
+			continue
+		}
+
+		weight := effectiveWeight(b)
+		s.currentWeights[i] += weight
+		total += weight
+
+		if selected == -1 || s.currentWeights[i] > s.currentWeights[selected] {
+			selected = i
+		}
+	}
+
+	if selected == -1 {
+		return nil
+	}
+
+	s.currentWeights[selected] -= total
+	return s.backends[selected]
+}
+
+func (s *wrrServerPool) AddBackend(b backend.Backend) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.backends = append(s.backends, b)
+	s.currentWeights = append(s.currentWeights, 0)
+}
+
+func (s *wrrServerPool) GetServerPoolSize() int {
+	return len(s.backends)
+}
+
+func (s *wrrServerPool) GetBackends() []backend.Backend {
+	return s.backends
+}
@@ -0,0 +1,109 @@
+package serverpool
+
+import (
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+)
+
+func newWeightedMockBackend(rawURL string, weight int) *mockBackend {
+	u, _ := url.Parse(rawURL)
+	mb := newMockBackend(u, httputil.NewSingleHostReverseProxy(u)).(*mockBackend)
+	mb.weight = weight
+	return mb
+}
+
+func TestWeightedRoundRobinServerPool(t *testing.T) {
+	pool := &wrrServerPool{
+		backends:       make([]backend.Backend, 0),
+		currentWeights: make([]int, 0),
+	}
+
+	// Test empty pool
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+
+	a := newWeightedMockBackend("http://localhost:8081", 5)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	c := newWeightedMockBackend("http://localhost:8083", 1)
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+	pool.AddBackend(c)
+
+	// Smooth weighted round-robin interleaves the light peers instead of
+	// sending the heavy peer five requests in a row.
+	want := []backend.Backend{a, a, b, a, c, a, a}
+	for i, w := range want {
+		if got := pool.GetNextValidPeer(); got != w {
+			t.Errorf("pick %d: GetNextValidPeer() = %v, want %v", i, got.GetURL(), w.GetURL())
+		}
+	}
+
+	// Test GetBackends
+	if got := len(pool.GetBackends()); got != 3 {
+		t.Errorf("GetBackends() returned %v backends, want 3", got)
+	}
+}
+
+func TestWeightedRoundRobinServerPool_Distribution(t *testing.T) {
+	pool := &wrrServerPool{}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 2)
+	c := newWeightedMockBackend("http://localhost:8083", 1)
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+	pool.AddBackend(c)
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 400; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+
+	if counts[a] != 100 || counts[b] != 200 || counts[c] != 100 {
+		t.Errorf("distribution = %d/%d/%d, want 100/200/100", counts[a], counts[b], counts[c])
+	}
+
+	// Dead peers are skipped and their share goes to the remaining ones
+	b.SetAlive(false)
+	counts = make(map[backend.Backend]int)
+	for i := 0; i < 100; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+
+	if counts[b] != 0 {
+		t.Errorf("dead backend received %d requests, want 0", counts[b])
+	}
+	if counts[a] != 50 || counts[c] != 50 {
+		t.Errorf("distribution = %d/%d, want 50/50", counts[a], counts[c])
+	}
+
+	// No alive peers
+	a.SetAlive(false)
+	c.SetAlive(false)
+	if got := pool.GetNextValidPeer(); got != nil {
+		t.Errorf("GetNextValidPeer() = %v, want nil", got)
+	}
+}
+
+func TestWeightedRoundRobinServerPool_SlowStart(t *testing.T) {
+	pool := &wrrServerPool{}
+
+	a := newWeightedMockBackend("http://localhost:8081", 1)
+	b := newWeightedMockBackend("http://localhost:8082", 1)
+	b.slowStartFactor = 0.25
+	pool.AddBackend(a)
+	pool.AddBackend(b)
+
+	counts := make(map[backend.Backend]int)
+	for i := 0; i < 500; i++ {
+		counts[pool.GetNextValidPeer()]++
+	}
+
+	if counts[a] != 400 || counts[b] != 100 {
+		t.Errorf("distribution = %d/%d, want 400/100", counts[a], counts[b])
+	}
+}
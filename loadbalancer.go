@@ -13,21 +13,38 @@ import (
 
 	"github.com/darshan-rambhia/eisodos/config"
 	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/headers"
+	"github.com/darshan-rambhia/eisodos/internal/router"
 	"github.com/darshan-rambhia/eisodos/internal/serverpool"
 )
 
 // LoadBalancer represents the main load balancer instance
 type LoadBalancer struct {
+	// serverPool holds the top level backends, which form the pool named
+	// config.DefaultPoolName
 	serverPool serverpool.ServerPool
-	server     *http.Server
-	mu         sync.RWMutex
+	pools      map[string]*namedPool
+	routes     *router.Table
+	// routePools holds, for routes with header rules, views of their pools
+	// applying those rules
+	routePools  map[*router.Route]map[string]*namedPool
+	defaultPool *namedPool
+	proxies     *serverpool.TrustedProxies
+	retry       *retryPolicy
+	hedge       *hedgePolicy
+	mirror      *mirrorPolicy
+	bodyBuffer  *bodyBufferPolicy
+	server      *http.Server
+	mu          sync.RWMutex
 }
 
 // LoadBalancerBuilder provides a fluent interface for building a LoadBalancer
 type LoadBalancerBuilder struct {
-	config     *config.Config
-	backends   []backend.Backend
-	serverPool serverpool.ServerPool
+	config       *config.Config
+	backends     []backend.Backend
+	poolBackends map[string][]backend.Backend
+	headerRules  map[string]config.HeaderRulesConfig
+	serverPool   serverpool.ServerPool
 }
 
 // NewLoadBalancerBuilder creates a new LoadBalancerBuilder
@@ -61,21 +78,232 @@ func (b *LoadBalancerBuilder) WithStrategy(strategy serverpool.LBStrategy) *Load
 	return b
 }
 
+// WithWaitQueue parks up to size requests for at most timeout while every
+// backend is at its connection limit. Each pool has a queue of its own. A
+// size of zero disables queueing.
+func (b *LoadBalancerBuilder) WithWaitQueue(size int, timeout time.Duration) *LoadBalancerBuilder {
+	b.config.Queue = config.QueueConfig{Size: size, Timeout: timeout}
+	return b
+}
+
+// WithHashing configures the key and ring size of hash based strategies
+func (b *LoadBalancerBuilder) WithHashing(hashing config.HashingConfig) *LoadBalancerBuilder {
+	b.config.Hashing = hashing
+	return b
+}
+
+// WithTrustedProxies sets the CIDRs whose forwarding header is used to
+// determine the client address
+func (b *LoadBalancerBuilder) WithTrustedProxies(cidrs []string) *LoadBalancerBuilder {
+	b.config.TrustedProxies = cidrs
+	return b
+}
+
+// WithClientIPHeader sets the forwarding header the trusted proxies write,
+// X-Forwarded-For by default or Forwarded
+func (b *LoadBalancerBuilder) WithClientIPHeader(header string) *LoadBalancerBuilder {
+	b.config.ClientIPHeader = header
+	return b
+}
+
+// WithStickySessions pins clients to a backend using a signed cookie
+func (b *LoadBalancerBuilder) WithStickySessions(sticky config.StickyConfig) *LoadBalancerBuilder {
+	b.config.Sticky = sticky
+	return b
+}
+
+// WithOutlierDetection ejects backends that fail live traffic
+func (b *LoadBalancerBuilder) WithOutlierDetection(outlier config.OutlierConfig) *LoadBalancerBuilder {
+	b.config.OutlierDetection = outlier
+	return b
+}
+
+// WithRetries retries failed requests on other backends
+func (b *LoadBalancerBuilder) WithRetries(retries config.RetryConfig) *LoadBalancerBuilder {
+	b.config.Retries = retries
+	return b
+}
+
+// WithRequestBuffering buffers request bodies so that they can be replayed
+func (b *LoadBalancerBuilder) WithRequestBuffering(buffering config.RequestBufferConfig) *LoadBalancerBuilder {
+	b.config.RequestBuffering = buffering
+	return b
+}
+
+// WithHedging sends slow reads to a second backend as well
+func (b *LoadBalancerBuilder) WithHedging(hedging config.HedgeConfig) *LoadBalancerBuilder {
+	b.config.Hedging = hedging
+	return b
+}
+
+// WithMirroring bounds the requests that routes mirror to shadow pools
+func (b *LoadBalancerBuilder) WithMirroring(mirroring config.MirroringConfig) *LoadBalancerBuilder {
+	b.config.Mirroring = mirroring
+	return b
+}
+
 // WithBackend adds a backend to the load balancer
-func (b *LoadBalancerBuilder) WithBackend(url *url.URL, proxy *httputil.ReverseProxy) *LoadBalancerBuilder {
-	b.backends = append(b.backends, backend.NewBackend(url, proxy))
+func (b *LoadBalancerBuilder) WithBackend(url *url.URL, proxy *httputil.ReverseProxy, opts ...backend.Option) *LoadBalancerBuilder {
+	b.backends = append(b.backends, backend.NewBackend(url, proxy, opts...))
+	return b
+}
+
+// WithPool declares a named pool that routes can send requests to
+func (b *LoadBalancerBuilder) WithPool(name string, strategy serverpool.LBStrategy) *LoadBalancerBuilder {
+	b.config.Pools = append(b.config.Pools, config.PoolConfig{Name: name, Strategy: &strategy})
+	return b
+}
+
+// WithPoolBackend adds a backend to the named pool, which must be declared
+// with WithPool or in the configuration
+func (b *LoadBalancerBuilder) WithPoolBackend(pool string, url *url.URL, proxy *httputil.ReverseProxy, opts ...backend.Option) *LoadBalancerBuilder {
+	if b.poolBackends == nil {
+		b.poolBackends = make(map[string][]backend.Backend)
+	}
+	b.poolBackends[pool] = append(b.poolBackends[pool], backend.NewBackend(url, proxy, opts...))
+	return b
+}
+
+// WithHeaderRules sets the header rules of the named pool, which is
+// config.DefaultPoolName for the top level backends, replacing those of the
+// configuration
+func (b *LoadBalancerBuilder) WithHeaderRules(pool string, rules config.HeaderRulesConfig) *LoadBalancerBuilder {
+	if b.headerRules == nil {
+		b.headerRules = make(map[string]config.HeaderRulesConfig)
+	}
+	b.headerRules[pool] = rules
+	return b
+}
+
+// WithRoutes sets the route table. Requests matching no route go to the
+// default pool.
+func (b *LoadBalancerBuilder) WithRoutes(routes []config.RouteConfig) *LoadBalancerBuilder {
+	b.config.Routes = routes
+	return b
+}
+
+// WithDefaultPool sets the pool serving requests that match no route
+func (b *LoadBalancerBuilder) WithDefaultPool(name string) *LoadBalancerBuilder {
+	b.config.DefaultPool = name
 	return b
 }
 
 // Build creates and returns a new LoadBalancer instance
 func (b *LoadBalancerBuilder) Build() (*LoadBalancer, error) {
-	pool, err := serverpool.NewServerPool(b.config.Strategy)
+	outlierPolicy, err := b.config.OutlierDetection.Policy()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create server pool: %w", err)
+		return nil, fmt.Errorf("failed to configure outlier detection: %w", err)
+	}
+
+	routes, err := b.config.RouteTable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure routes: %w", err)
+	}
+
+	proxies, err := serverpool.ParseTrustedProxies(b.config.TrustedProxies, b.config.ClientIPHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure trusted proxies: %w", err)
 	}
 
 	lb := &LoadBalancer{
-		serverPool: pool,
+		pools:      make(map[string]*namedPool),
+		routePools: make(map[*router.Route]map[string]*namedPool),
+		proxies:    proxies,
+	}
+
+	pool, err := b.newServerPool(config.DefaultPoolName, b.config.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	lb.serverPool = pool
+	if err := lb.addPool(config.DefaultPoolName, pool, b.poolHeaderRules(config.DefaultPoolName, b.config.HeaderRules)); err != nil {
+		return nil, err
+	}
+
+	for _, pc := range b.config.Pools {
+		if _, ok := lb.pools[pc.Name]; ok {
+			return nil, fmt.Errorf("duplicate pool %q", pc.Name)
+		}
+
+		strategy := b.config.Strategy
+		if pc.Strategy != nil {
+			strategy = *pc.Strategy
+		}
+
+		pool, err := b.newServerPool(pc.Name, strategy)
+		if err != nil {
+			return nil, err
+		}
+		if err := lb.addPool(pc.Name, pool, b.poolHeaderRules(pc.Name, pc.HeaderRules)); err != nil {
+			return nil, err
+		}
+	}
+	for name := range b.headerRules {
+		if _, ok := lb.pools[name]; !ok {
+			return nil, fmt.Errorf("header rules set for unknown pool %q", name)
+		}
+	}
+
+	if b.config.Retries.MaxAttempts > 1 {
+		lb.retry = newRetryPolicy(b.config.Retries)
+	}
+
+	if b.config.Hedging.Enabled {
+		lb.hedge = newHedgePolicy(b.config.Hedging)
+	}
+
+	// Each pool queues for its own backends and spends its own retry and
+	// hedge budgets, so that a slot freed in one pool is never handed to a
+	// request waiting for another and a failing pool cannot exhaust the
+	// budgets of the rest
+	for _, np := range lb.pools {
+		if b.config.Queue.Size > 0 {
+			np.waitQueue = serverpool.NewWaitQueue(b.config.Queue.Size, b.config.Queue.Timeout)
+		}
+		if lb.retry != nil {
+			np.retryBudget = lb.retry.newBudget()
+		}
+		if lb.hedge != nil {
+			np.hedgeBudget = lb.hedge.newBudget()
+		}
+	}
+
+	for i, route := range routes.Routes() {
+		for _, pool := range route.Pools() {
+			if _, ok := lb.pools[pool]; !ok {
+				return nil, fmt.Errorf("failed to configure routes: unknown pool %q", pool)
+			}
+		}
+		if route.Mirror != nil && lb.mirror == nil {
+			lb.mirror = newMirrorPolicy(b.config.Mirroring)
+		}
+
+		request, response, err := compileHeaderRules(b.config.Routes[i].HeaderRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure routes: route %d: invalid header rules: %w", i, err)
+		}
+		if request != nil || response != nil {
+			views := make(map[string]*namedPool)
+			for _, pool := range route.Pools() {
+				views[pool] = lb.pools[pool].withHeaderRules(request, response)
+			}
+			lb.routePools[route] = views
+		}
+	}
+	if len(routes.Routes()) > 0 {
+		lb.routes = routes
+	}
+
+	defaultPool := b.config.DefaultPool
+	if defaultPool == "" {
+		defaultPool = config.DefaultPoolName
+	}
+	if lb.defaultPool = lb.pools[defaultPool]; lb.defaultPool == nil {
+		return nil, fmt.Errorf("unknown default pool %q", defaultPool)
+	}
+
+	if b.config.RequestBuffering.MaxBytes > 0 {
+		lb.bodyBuffer = newBodyBufferPolicy(b.config.RequestBuffering)
 	}
 
 	// Create HTTP server
@@ -84,17 +312,112 @@ func (b *LoadBalancerBuilder) Build() (*LoadBalancer, error) {
 		Handler: lb,
 	}
 
-	// Add backends
+	// Add backends. They start at full weight, as there is no traffic yet
+	// that slow start could shift to them gradually.
 	for _, b := range b.backends {
-		lb.AddBackend(b)
+		lb.serverPool.AddBackend(b)
+	}
+	for name, backends := range b.poolBackends {
+		np, ok := lb.pools[name]
+		if !ok {
+			return nil, fmt.Errorf("backend added to unknown pool %q", name)
+		}
+		for _, b := range backends {
+			np.pool.AddBackend(b)
+		}
 	}
 
-	// Start health check routine
-	go lb.startHealthCheck(b.config.HealthCheckInterval)
+	for _, np := range lb.pools {
+		// Start health check routine
+		go startHealthCheck(np.pool, b.config.HealthCheckInterval)
+
+		if b.config.OutlierDetection.Enabled {
+			go serverpool.NewOutlierDetector(np.pool, outlierPolicy).Run(context.Background())
+		}
+	}
 
 	return lb, nil
 }
 
+// poolHeaderRules returns the header rules of the named pool, those set with
+// WithHeaderRules taking precedence over configured
+func (b *LoadBalancerBuilder) poolHeaderRules(name string, configured config.HeaderRulesConfig) config.HeaderRulesConfig {
+	if rules, ok := b.headerRules[name]; ok {
+		return rules
+	}
+	return configured
+}
+
+// addPool registers pool under name with its header rules
+func (lb *LoadBalancer) addPool(name string, pool serverpool.ServerPool, rules config.HeaderRulesConfig) error {
+	np := newNamedPool(name, pool)
+
+	var err error
+	if np.requestHeaders, np.responseHeaders, err = compileHeaderRules(rules); err != nil {
+		return fmt.Errorf("invalid header rules of pool %q: %w", name, err)
+	}
+	lb.pools[name] = np
+	return nil
+}
+
+// newServerPool creates the server pool of the named pool with the hashing
+// and session settings of the configuration
+func (b *LoadBalancerBuilder) newServerPool(name string, strategy serverpool.LBStrategy) (serverpool.ServerPool, error) {
+	hashKey, err := serverpool.ParseHashKey(b.config.Hashing.Key, b.config.Hashing.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server pool: %w", err)
+	}
+
+	proxies, err := serverpool.ParseTrustedProxies(b.config.TrustedProxies, b.config.ClientIPHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server pool: %w", err)
+	}
+
+	opts := []serverpool.Option{
+		serverpool.WithHashKey(hashKey),
+		serverpool.WithTrustedProxies(proxies),
+		serverpool.WithVirtualNodes(b.config.Hashing.VirtualNodes),
+	}
+	if b.config.Hashing.Epsilon != nil {
+		opts = append(opts, serverpool.WithLoadEpsilon(*b.config.Hashing.Epsilon))
+	}
+
+	pool, err := serverpool.NewServerPool(strategy, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create server pool %q: %w", name, err)
+	}
+
+	if b.config.Sticky.Enabled {
+		if err := b.config.Sticky.Validate(); err != nil {
+			return nil, fmt.Errorf("failed to configure sticky sessions: %w", err)
+		}
+		sameSite, err := serverpool.ParseSameSite(b.config.Sticky.SameSite)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure sticky sessions: %w", err)
+		}
+
+		// Every pool has its own cookie so that clients can be pinned in
+		// several pools at once
+		cookieName := b.config.Sticky.CookieName
+		if cookieName == "" {
+			cookieName = serverpool.DefaultStickyCookieName
+		}
+		if name != config.DefaultPoolName {
+			cookieName += "_" + name
+		}
+
+		pool = serverpool.NewStickyServerPool(pool, serverpool.StickyOptions{
+			CookieName: cookieName,
+			TTL:        b.config.Sticky.TTL,
+			SameSite:   sameSite,
+			Secure:     b.config.Sticky.Secure,
+			Key:        []byte(b.config.Sticky.Key),
+		})
+	}
+
+	return pool, nil
+}
+
 // Config holds the configuration for the load balancer
 type Config struct {
 	Port                int
@@ -111,7 +434,9 @@ func NewLoadBalancer(cfg Config) (*LoadBalancer, error) {
 
 	lb := &LoadBalancer{
 		serverPool: pool,
+		pools:      map[string]*namedPool{config.DefaultPoolName: newNamedPool(config.DefaultPoolName, pool)},
 	}
+	lb.defaultPool = lb.pools[config.DefaultPoolName]
 
 	// Create HTTP server
 	lb.server = &http.Server{
@@ -120,32 +445,92 @@ func NewLoadBalancer(cfg Config) (*LoadBalancer, error) {
 	}
 
 	// Start health check routine
-	go lb.startHealthCheck(cfg.HealthCheckInterval)
+	go startHealthCheck(pool, cfg.HealthCheckInterval)
 
 	return lb, nil
 }
 
 // ServeHTTP implements the http.Handler interface
 func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	peer := lb.serverPool.GetNextValidPeer()
+	var buf *bodyBuffer
+	if lb.bodyBuffer != nil {
+		var err error
+		if buf, err = lb.bodyBuffer.buffer(r); err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		defer buf.Close()
+	}
+
+	np, shadow := lb.route(r)
+	ensureRequestID(r, np, shadow)
+	if shadow != nil {
+		lb.mirror.mirror(lb, r, buf, shadow)
+	}
+	next := func() backend.Backend {
+		return serverpool.NextValidPeer(np.pool, r)
+	}
+
+	peer := next()
+	if peer == nil && np.waitQueue != nil && serverpool.IsSaturated(np.pool) {
+		peer, _ = np.waitQueue.Wait(r.Context(), next)
+	}
+
 	if peer == nil {
 		http.Error(w, "Service not available", http.StatusServiceUnavailable)
 		return
 	}
 
-	peer.Serve(w, r)
+	switch {
+	case lb.retry != nil:
+		lb.serveWithRetries(w, r, np, peer)
+	case lb.hedge != nil && hedgeable(r):
+		lb.serveHedged(newHedgeRace(w), r, np, peer, []backend.Backend{peer}, 0)
+	default:
+		if binder, ok := np.pool.(serverpool.PeerBinder); ok {
+			binder.BindPeer(w, r, peer)
+		}
+		lb.servePeer(w, r, np, peer)
+	}
+
+	if np.waitQueue != nil {
+		np.waitQueue.Release()
+	}
 }
 
-// startHealthCheck runs the health check routine
-func (lb *LoadBalancer) startHealthCheck(interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// route returns the pool of the first route matching r, or the default
+// pool, and the pool receiving a copy of r if the route mirrors it
+func (lb *LoadBalancer) route(r *http.Request) (np *namedPool, shadow *namedPool) {
+	if lb.routes != nil {
+		if route := lb.routes.Match(r); route != nil {
+			pools := lb.pools
+			if views, ok := lb.routePools[route]; ok {
+				pools = views
+			}
+
+			if mirror := route.PickMirror(); mirror != "" {
+				shadow = pools[mirror]
+			}
+			return pools[route.PickPool(r)], shadow
+		}
+	}
+	return lb.defaultPool, nil
+}
 
-	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		serverpool.HealthCheck(ctx, lb.serverPool)
-		cancel()
+// SetSplitWeights changes the traffic split of the named route while it
+// serves requests, e.g. to grow a canary. Pools of the split missing from
+// weights stop receiving new requests.
+func (lb *LoadBalancer) SetSplitWeights(route string, weights map[string]int) error {
+	if lb.routes == nil {
+		return fmt.Errorf("unknown route %q", route)
 	}
+	return lb.routes.SetWeights(route, weights)
+}
+
+// startHealthCheck runs the health check routine of pool. interval applies
+// to backends whose health policy does not set their own.
+func startHealthCheck(pool serverpool.ServerPool, interval time.Duration) {
+	serverpool.NewHealthMonitor(pool, interval).Run(context.Background())
 }
 
 // Start starts the load balancer server
@@ -164,10 +549,12 @@ func (lb *LoadBalancer) Stop(ctx context.Context) error {
 	return lb.server.Shutdown(ctx)
 }
 
-// AddBackend adds a new backend to the server pool
+// AddBackend adds a new backend to the server pool, ramping up its traffic
+// if it has a slow start policy
 func (lb *LoadBalancer) AddBackend(b backend.Backend) {
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
+	b.StartSlowStart()
 	lb.serverPool.AddBackend(b)
 }
 
@@ -185,6 +572,21 @@ func (lb *LoadBalancer) GetServerPoolSize() int {
 	return lb.serverPool.GetServerPoolSize()
 }
 
+// GetCircuitStates returns the circuit breaker state of every backend of the
+// named pool, keyed by backend URL: closed, open or half-open
+func (lb *LoadBalancer) GetCircuitStates(pool string) (map[string]string, error) {
+	np, ok := lb.pools[pool]
+	if !ok {
+		return nil, fmt.Errorf("unknown pool %q", pool)
+	}
+
+	states := make(map[string]string)
+	for _, b := range np.pool.GetBackends() {
+		states[b.GetURL().String()] = b.GetCircuitState().String()
+	}
+	return states, nil
+}
+
 // GetPort returns the port the load balancer is listening on
 func (lb *LoadBalancer) GetPort() int {
 	lb.mu.RLock()
@@ -203,3 +605,31 @@ func (lb *LoadBalancer) GetPort() int {
 	}
 	return p
 }
+
+// namedPool is a pool of backends that routes send requests to
+type namedPool struct {
+	name string
+	pool serverpool.ServerPool
+	// latency holds recent response times, from which the hedge delay is
+	// estimated
+	latency *latencyWindow
+	// waitQueue parks requests while every backend of the pool is at its
+	// connection limit
+	waitQueue *serverpool.WaitQueue
+	// retryBudget and hedgeBudget cap the retries and hedges in flight
+	// relative to the requests of the pool
+	retryBudget *serverpool.RetryBudget
+	hedgeBudget *serverpool.RetryBudget
+	// requestHeaders and responseHeaders rewrite the headers of the
+	// requests served by the pool
+	requestHeaders  *headers.Rules
+	responseHeaders *headers.Rules
+}
+
+func newNamedPool(name string, pool serverpool.ServerPool) *namedPool {
+	return &namedPool{
+		name:    name,
+		pool:    pool,
+		latency: newLatencyWindow(latencyWindowSize),
+	}
+}
@@ -0,0 +1,231 @@
+package eisodos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
+
+func TestLoadBalancerRoutes(t *testing.T) {
+	web := newTestUpstream(t, respond(http.StatusOK, "web"))
+	api := newTestUpstream(t, respond(http.StatusOK, "api"))
+
+	build := func(defaultPool string) *LoadBalancer {
+		apiURL := mustParseURL(t, api.URL)
+		webURL := mustParseURL(t, web.URL)
+
+		lb, err := NewLoadBalancerBuilder().
+			WithHealthCheckInterval(time.Hour).
+			WithBackend(webURL, httputil.NewSingleHostReverseProxy(webURL)).
+			WithPool("api", serverpool.LeastConnected).
+			WithPoolBackend("api", apiURL, httputil.NewSingleHostReverseProxy(apiURL)).
+			WithRoutes([]config.RouteConfig{{Host: "api.example.com", Pool: "api"}}).
+			WithDefaultPool(defaultPool).
+			Build()
+		require.NoError(t, err)
+		return lb
+	}
+
+	serve := func(lb *LoadBalancer, target string) string {
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("GET", target, nil))
+		return recorder.Body.String()
+	}
+
+	lb := build("")
+	assert.Equal(t, "api", serve(lb, "http://api.example.com/"))
+	assert.Equal(t, "web", serve(lb, "http://www.example.com/"))
+
+	// The default route can send unmatched requests to a named pool
+	lb = build("api")
+	assert.Equal(t, "api", serve(lb, "http://www.example.com/"))
+}
+
+func TestLoadBalancerBuilderPoolErrors(t *testing.T) {
+	u := mustParseURL(t, "http://localhost:8081")
+
+	_, err := NewLoadBalancerBuilder().
+		WithPoolBackend("api", u, httputil.NewSingleHostReverseProxy(u)).
+		Build()
+	assert.ErrorContains(t, err, `backend added to unknown pool "api"`)
+
+	_, err = NewLoadBalancerBuilder().
+		WithRoutes([]config.RouteConfig{{Path: "/api", Pool: "api"}}).
+		Build()
+	assert.ErrorContains(t, err, `unknown pool "api"`)
+
+	_, err = NewLoadBalancerBuilder().
+		WithDefaultPool("api").
+		Build()
+	assert.ErrorContains(t, err, `unknown default pool "api"`)
+}
+
+func TestLoadBalancerBuilderStickyKey(t *testing.T) {
+	// Cookies signed with a short key could be forged
+	for _, key := range []string{"", "short"} {
+		_, err := NewLoadBalancerBuilder().
+			WithStickySessions(config.StickyConfig{Enabled: true, Key: key}).
+			Build()
+		assert.ErrorContains(t, err, "failed to configure sticky sessions: key must be at least 16 bytes")
+	}
+
+	_, err := NewLoadBalancerBuilder().
+		WithStickySessions(config.StickyConfig{Enabled: true, Key: "0123456789abcdef"}).
+		Build()
+	assert.NoError(t, err)
+}
+
+func TestLoadBalancerSplit(t *testing.T) {
+	stable := newTestUpstream(t, respond(http.StatusOK, "stable"))
+	canary := newTestUpstream(t, respond(http.StatusOK, "canary"))
+	stableURL := mustParseURL(t, stable.URL)
+	canaryURL := mustParseURL(t, canary.URL)
+
+	lb, err := NewLoadBalancerBuilder().
+		WithHealthCheckInterval(time.Hour).
+		WithBackend(stableURL, httputil.NewSingleHostReverseProxy(stableURL)).
+		WithPool("canary", serverpool.RoundRobin).
+		WithPoolBackend("canary", canaryURL, httputil.NewSingleHostReverseProxy(canaryURL)).
+		WithRoutes([]config.RouteConfig{{
+			Name:  "web",
+			Split: []config.SplitConfig{{Pool: config.DefaultPoolName, Weight: 1}, {Pool: "canary", Weight: 0}},
+			PinBy: config.PinConfig{Cookie: "uid"},
+		}}).
+		Build()
+	require.NoError(t, err)
+
+	serve := func(user string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: "uid", Value: user})
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, req)
+		return recorder.Body.String()
+	}
+
+	assert.Equal(t, "stable", serve("alice"))
+
+	require.NoError(t, lb.SetSplitWeights("web", map[string]int{"canary": 1}))
+	assert.Equal(t, "canary", serve("alice"))
+
+	assert.ErrorContains(t, lb.SetSplitWeights("api", map[string]int{"canary": 1}), `unknown route "api"`)
+}
+
+func TestLoadBalancerCircuitStates(t *testing.T) {
+	failing := newTestUpstream(t, respond(http.StatusInternalServerError, "failing"))
+	healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+	failingURL := mustParseURL(t, failing.URL)
+	healthyURL := mustParseURL(t, healthy.URL)
+	breaker := backend.WithCircuitBreaker(backend.CircuitBreakerPolicy{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	lb, err := NewLoadBalancerBuilder().
+		WithHealthCheckInterval(time.Hour).
+		WithBackend(healthyURL, httputil.NewSingleHostReverseProxy(healthyURL), breaker).
+		WithPool("api", serverpool.RoundRobin).
+		WithPoolBackend("api", failingURL, httputil.NewSingleHostReverseProxy(failingURL), breaker).
+		WithRoutes([]config.RouteConfig{{Path: "/api", Pool: "api"}}).
+		Build()
+	require.NoError(t, err)
+
+	states, err := lb.GetCircuitStates("api")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{failing.URL: "closed"}, states)
+
+	lb.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api", nil))
+
+	states, err = lb.GetCircuitStates("api")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{failing.URL: "open"}, states)
+
+	// Other pools are unaffected
+	states, err = lb.GetCircuitStates(config.DefaultPoolName)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{healthy.URL: "closed"}, states)
+
+	_, err = lb.GetCircuitStates("web")
+	assert.ErrorContains(t, err, `unknown pool "web"`)
+}
+
+func TestLoadBalancerWaitQueuePerPool(t *testing.T) {
+	// Both upstreams hold requests until their gate opens, keeping the only
+	// connection slot of each pool busy
+	webGate, apiGate := make(chan struct{}), make(chan struct{})
+	web := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) { <-webGate })
+	api := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		<-apiGate
+		w.Write([]byte("api"))
+	})
+	webURL := mustParseURL(t, web.URL)
+	apiURL := mustParseURL(t, api.URL)
+
+	lb, err := NewLoadBalancerBuilder().
+		WithHealthCheckInterval(time.Hour).
+		WithWaitQueue(4, 2*time.Second).
+		WithBackend(webURL, httputil.NewSingleHostReverseProxy(webURL), backend.WithMaxConns(1)).
+		WithPool("api", serverpool.RoundRobin).
+		WithPoolBackend("api", apiURL, httputil.NewSingleHostReverseProxy(apiURL), backend.WithMaxConns(1)).
+		WithRoutes([]config.RouteConfig{{Path: "/api", Pool: "api"}}).
+		Build()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+	t.Cleanup(func() { close(webGate) })
+	serve := func(target string) <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", target, nil))
+			done <- recorder
+		}()
+		return done
+	}
+	queued := func(pool string, n int) func() bool {
+		return func() bool { return lb.pools[pool].waitQueue.Len() == n }
+	}
+	saturated := func(pool string) func() bool {
+		return func() bool { return serverpool.IsSaturated(lb.pools[pool].pool) }
+	}
+
+	// A request for the web pool waits ahead of one for the api pool
+	serve("/")
+	require.Eventually(t, saturated(config.DefaultPoolName), time.Second, time.Millisecond)
+	serve("/")
+	require.Eventually(t, queued(config.DefaultPoolName, 1), time.Second, time.Millisecond)
+
+	first := serve("/api")
+	require.Eventually(t, saturated("api"), time.Second, time.Millisecond)
+	second := serve("/api")
+	require.Eventually(t, queued("api", 1), time.Second, time.Millisecond)
+
+	// The slot freed in the api pool goes to the api waiter, not to the
+	// web waiter at the head of the line
+	close(apiGate)
+	for _, done := range []<-chan *httptest.ResponseRecorder{first, second} {
+		select {
+		case recorder := <-done:
+			assert.Equal(t, "api", recorder.Body.String())
+		case <-time.After(time.Second):
+			t.Fatal("api request was not served")
+		}
+	}
+}
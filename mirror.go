@@ -0,0 +1,121 @@
+package eisodos
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+)
+
+const (
+	// defaultMirrorConcurrency is how many mirrored requests may be in
+	// flight at once
+	defaultMirrorConcurrency = 64
+	// defaultMirrorTimeout is how long a mirrored request may take
+	defaultMirrorTimeout = 30 * time.Second
+	// defaultMirrorHeader marks mirrored requests
+	defaultMirrorHeader = "X-Eisodos-Mirror"
+)
+
+// mirrorPolicy sends fire-and-forget copies of requests to shadow pools
+type mirrorPolicy struct {
+	header  string
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+func newMirrorPolicy(cfg config.MirroringConfig) *mirrorPolicy {
+	p := &mirrorPolicy{
+		header:  cfg.Header,
+		timeout: cfg.Timeout,
+		slots:   make(chan struct{}, defaultMirrorConcurrency),
+	}
+	if p.header == "" {
+		p.header = defaultMirrorHeader
+	}
+	if p.timeout <= 0 {
+		p.timeout = defaultMirrorTimeout
+	}
+	if cfg.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return p
+}
+
+// mirrorable reports whether a copy of r can be sent without touching the
+// client's request: its body, if any, must be replayable
+func mirrorable(r *http.Request) bool {
+	if r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// mirror sends a copy of r to a peer of np in the background. The copy is
+// dropped when the concurrency limit is reached. buf, the buffered body of r
+// if any, is kept open until the copy is done.
+func (p *mirrorPolicy) mirror(lb *LoadBalancer, r *http.Request, buf *bodyBuffer, np *namedPool) {
+	if !mirrorable(r) {
+		return
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		slog.Debug("Dropping mirrored request", "URL", r.URL.String(), "pool", np.name)
+		return
+	}
+
+	// The copy outlives the client request, so it must not be cancelled
+	// with it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), p.timeout)
+	mr := r.Clone(ctx)
+	mr.Header.Set(p.header, "1")
+	mr.Body = http.NoBody
+	buf.retain()
+
+	go func() {
+		defer func() { <-p.slots }()
+		defer cancel()
+		defer buf.Close()
+		defer func() {
+			// ReverseProxy aborts failed response copies with a panic that
+			// only the HTTP server would otherwise recover
+			if err := recover(); err != nil && err != http.ErrAbortHandler {
+				panic(err)
+			}
+		}()
+
+		if mr.GetBody != nil {
+			body, err := mr.GetBody()
+			if err != nil {
+				return
+			}
+			mr.Body = body
+		}
+
+		peer := serverpool.NextValidPeer(np.pool, mr)
+		if peer == nil {
+			return
+		}
+		lb.servePeer(&discardWriter{header: make(http.Header)}, mr, np, peer)
+	}()
+}
+
+// discardWriter swallows the response of a mirrored request
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) WriteHeader(int) {}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,142 @@
+package eisodos
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mirroredRequest is what the shadow upstream of a test received
+type mirroredRequest struct {
+	marker string
+	body   string
+}
+
+// newMirroringLoadBalancer builds a load balancer serving every request from
+// primary and mirroring them all to shadow
+func newMirroringLoadBalancer(t *testing.T, cfg *config.Config, primary, shadow string) *LoadBalancer {
+	t.Helper()
+
+	primaryURL := mustParseURL(t, primary)
+	shadowURL := mustParseURL(t, shadow)
+
+	cfg.HealthCheckInterval = time.Hour
+	lb, err := NewLoadBalancerBuilder().
+		WithConfig(cfg).
+		WithBackend(primaryURL, httputil.NewSingleHostReverseProxy(primaryURL)).
+		WithPool("shadow", serverpool.RoundRobin).
+		WithPoolBackend("shadow", shadowURL, httputil.NewSingleHostReverseProxy(shadowURL)).
+		WithRoutes([]config.RouteConfig{{
+			Pool:   config.DefaultPoolName,
+			Mirror: &config.MirrorConfig{Pool: "shadow", Percent: 100},
+		}}).
+		Build()
+	require.NoError(t, err)
+	return lb
+}
+
+// recordingUpstream sends what it receives on a channel after delay
+func recordingUpstream(t *testing.T, delay time.Duration, header string) (*testUpstream, chan mirroredRequest) {
+	t.Helper()
+
+	received := make(chan mirroredRequest, 16)
+	upstream := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		body, _ := io.ReadAll(r.Body)
+		received <- mirroredRequest{marker: r.Header.Get(header), body: string(body)}
+		respond(http.StatusInternalServerError, "shadow")(w, r)
+	})
+	return upstream, received
+}
+
+func TestMirroring(t *testing.T) {
+	t.Run("slow shadow does not delay the client", func(t *testing.T) {
+		primary := newTestUpstream(t, respond(http.StatusOK, "primary"))
+		shadow, received := recordingUpstream(t, 300*time.Millisecond, "X-Eisodos-Mirror")
+		lb := newMirroringLoadBalancer(t, config.DefaultConfig(), primary.URL, shadow.URL)
+
+		recorder := httptest.NewRecorder()
+		start := time.Now()
+		lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		assert.Less(t, time.Since(start), 200*time.Millisecond)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "primary", recorder.Body.String())
+
+		select {
+		case got := <-received:
+			assert.Equal(t, "1", got.marker)
+		case <-time.After(time.Second):
+			t.Fatal("the request was not mirrored")
+		}
+	})
+
+	t.Run("buffered bodies outlive the client request", func(t *testing.T) {
+		primary := newTestUpstream(t, respond(http.StatusOK, "primary"))
+		shadow, received := recordingUpstream(t, 50*time.Millisecond, "X-Shadow")
+
+		cfg := config.DefaultConfig()
+		cfg.RequestBuffering = config.RequestBufferConfig{MaxBytes: 1 << 10, MemoryBytes: 4, TempDir: t.TempDir()}
+		cfg.Mirroring = config.MirroringConfig{Header: "X-Shadow"}
+		lb := newMirroringLoadBalancer(t, cfg, primary.URL, shadow.URL)
+
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("POST", "/", strings.NewReader("spilled payload")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		select {
+		case got := <-received:
+			assert.Equal(t, mirroredRequest{marker: "1", body: "spilled payload"}, got)
+		case <-time.After(time.Second):
+			t.Fatal("the request was not mirrored")
+		}
+	})
+
+	t.Run("unbuffered bodies are not mirrored", func(t *testing.T) {
+		primary := newTestUpstream(t, respond(http.StatusOK, "primary"))
+		shadow, _ := recordingUpstream(t, 0, "X-Eisodos-Mirror")
+		lb := newMirroringLoadBalancer(t, config.DefaultConfig(), primary.URL, shadow.URL)
+
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("POST", "/", strings.NewReader("payload")))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(0), shadow.hits.Load())
+	})
+
+	t.Run("mirrors beyond the limit are dropped", func(t *testing.T) {
+		primary := newTestUpstream(t, respond(http.StatusOK, "primary"))
+		release := make(chan struct{})
+		shadow := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+		t.Cleanup(func() { close(release) })
+
+		cfg := config.DefaultConfig()
+		cfg.Mirroring = config.MirroringConfig{MaxConcurrent: 1}
+		lb := newMirroringLoadBalancer(t, cfg, primary.URL, shadow.URL)
+
+		for i := 0; i < 3; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+
+		assert.Eventually(t, func() bool {
+			return shadow.hits.Load() == 1
+		}, time.Second, 10*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, int32(1), shadow.hits.Load())
+		assert.Equal(t, int32(3), primary.hits.Load())
+	})
+}
@@ -0,0 +1,267 @@
+package eisodos
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/backend"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+)
+
+// defaultRetryOn are the statuses retried when none are configured
+var defaultRetryOn = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// retryPolicy decides whether a failed attempt is sent to another peer
+type retryPolicy struct {
+	maxAttempts   int
+	perTryTimeout time.Duration
+	retryOn       map[int]bool
+	retryUnsent   bool
+	budget        config.RetryBudgetConfig
+}
+
+func newRetryPolicy(cfg config.RetryConfig) *retryPolicy {
+	retryOn := cfg.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = defaultRetryOn
+	}
+
+	p := &retryPolicy{
+		maxAttempts:   cfg.MaxAttempts,
+		perTryTimeout: cfg.PerTryTimeout,
+		retryOn:       make(map[int]bool, len(retryOn)),
+		retryUnsent:   cfg.RetryUnsent,
+		budget:        cfg.Budget,
+	}
+	for _, status := range retryOn {
+		p.retryOn[status] = true
+	}
+	return p
+}
+
+// newBudget creates the retry budget of one pool
+func (p *retryPolicy) newBudget() *serverpool.RetryBudget {
+	return serverpool.NewRetryBudget(p.budget.Ratio, p.budget.MinRetries)
+}
+
+// retriable reports whether an attempt at r that ended with status, or with
+// err when the backend did not respond, may be repeated on another peer
+func (p *retryPolicy) retriable(r *http.Request, status int, err error) bool {
+	// The client has gone away
+	if r.Context().Err() != nil {
+		return false
+	}
+
+	if !replayable(r) {
+		return false
+	}
+
+	if isIdempotent(r) {
+		return err != nil || p.retryOn[status]
+	}
+
+	// Anything else may only be repeated if the backend never saw it
+	return p.retryUnsent && isUnsent(err)
+}
+
+// isIdempotent reports whether r may safely be sent more than once, using
+// the same rules as net/http's transport
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	_, key := r.Header["Idempotency-Key"]
+	_, xKey := r.Header["X-Idempotency-Key"]
+	return key || xKey
+}
+
+// replayable reports whether the body of r can be sent again, either because
+// there is none or because it has been buffered
+func replayable(r *http.Request) bool {
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// isUnsent reports whether err means the request never left the load
+// balancer, because no connection to the backend could be made
+func isUnsent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// serveWithRetries serves r on peer and, while the policy and budget allow,
+// on further untried peers of np until an attempt produces a response worth
+// returning to the client. With hedging, each attempt is a hedge race whose
+// answer is retried like that of a single peer.
+func (lb *LoadBalancer) serveWithRetries(w http.ResponseWriter, r *http.Request, np *namedPool, peer backend.Backend) {
+	p := lb.retry
+	budget := np.retryBudget
+	budget.Begin()
+	defer budget.End()
+
+	hedged := lb.hedge != nil && hedgeable(r)
+	tried := make([]backend.Backend, 0, p.maxAttempts)
+	retrying := false
+	for attempt := 1; ; attempt++ {
+		tried = append(tried, peer)
+
+		var race *hedgeRace
+		var next backend.Backend
+		aw := &attemptWriter{
+			rw:     w,
+			header: make(http.Header),
+			retry: func(status int, err error) bool {
+				if attempt >= p.maxAttempts || !p.retriable(r, status, err) {
+					return false
+				}
+
+				exclude := tried
+				if race != nil {
+					exclude = append(slices.Clone(tried), race.launched()...)
+				}
+				if next = serverpool.NextUntriedPeer(np.pool, r, exclude); next == nil {
+					return false
+				}
+				return budget.Acquire()
+			},
+		}
+
+		if hedged {
+			race = newHedgeRace(aw)
+			lb.serveHedged(race, r, np, peer, tried, p.perTryTimeout)
+			tried = append(tried, race.launched()...)
+		} else {
+			lb.serveAttempt(aw, r, np, peer, p.perTryTimeout)
+		}
+		if retrying {
+			budget.Release()
+		}
+		if !aw.discarded {
+			return
+		}
+
+		slog.Debug(
+			"Retrying request",
+			"URL", r.URL.String(),
+			"attempt", attempt+1,
+			"from", peer.GetURL().String(),
+			"to", next.GetURL().String(),
+			"status", aw.status,
+			"error", aw.err,
+		)
+		peer = next
+		retrying = true
+	}
+}
+
+// serveAttempt sends one attempt at r to peer, bounded by timeout if set and
+// with a fresh copy of any buffered body
+func (lb *LoadBalancer) serveAttempt(w http.ResponseWriter, r *http.Request, np *namedPool, peer backend.Backend, timeout time.Duration) {
+	ctx := r.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	r = r.WithContext(ctx)
+
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			http.Error(w, "Failed to replay request body", http.StatusInternalServerError)
+			return
+		}
+		r.Body = body
+	}
+
+	if binder, ok := np.pool.(serverpool.PeerBinder); ok {
+		binder.BindPeer(w, r, peer)
+	}
+	lb.servePeer(w, r, np, peer)
+}
+
+// attemptWriter holds back the response of an attempt until its status is
+// known. Retriable responses are discarded so that the next attempt can
+// answer the client; anything else is passed through.
+type attemptWriter struct {
+	rw     http.ResponseWriter
+	header http.Header
+	retry  func(status int, err error) bool
+
+	status    int
+	err       error
+	committed bool
+	discarded bool
+}
+
+func (w *attemptWriter) Header() http.Header {
+	if w.committed {
+		return w.rw.Header()
+	}
+	return w.header
+}
+
+func (w *attemptWriter) WriteHeader(status int) {
+	if w.committed || w.discarded {
+		return
+	}
+
+	// Informational responses are passed through without deciding the attempt
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		copyHeader(w.rw.Header(), w.header)
+		w.rw.WriteHeader(status)
+		return
+	}
+
+	w.status = status
+	if w.retry(status, w.err) {
+		w.discarded = true
+		return
+	}
+
+	copyHeader(w.rw.Header(), w.header)
+	w.rw.WriteHeader(status)
+	w.committed = true
+}
+
+func (w *attemptWriter) Write(p []byte) (int, error) {
+	if !w.committed && !w.discarded {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.discarded {
+		return len(p), nil
+	}
+	return w.rw.Write(p)
+}
+
+// ObserveProxyError records why the backend failed to respond, for the
+// retry decision made when the error response is written
+func (w *attemptWriter) ObserveProxyError(err error) {
+	w.err = err
+}
+
+// Flush only reaches the client once the attempt has been committed
+func (w *attemptWriter) Flush() {
+	if w.committed {
+		http.NewResponseController(w.rw).Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the client connection, e.g. to
+// hijack it for protocol upgrades
+func (w *attemptWriter) Unwrap() http.ResponseWriter {
+	return w.rw
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
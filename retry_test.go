@@ -0,0 +1,254 @@
+package eisodos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darshan-rambhia/eisodos/config"
+	"github.com/darshan-rambhia/eisodos/internal/serverpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUpstream is a backend server counting the requests it receives
+type testUpstream struct {
+	*httptest.Server
+	hits atomic.Int32
+}
+
+func newTestUpstream(t *testing.T, handler http.HandlerFunc) *testUpstream {
+	t.Helper()
+
+	u := &testUpstream{}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream", body)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// newTestLoadBalancer builds a round-robin load balancer over upstreams
+func newTestLoadBalancer(t *testing.T, retries config.RetryConfig, upstreams ...string) *LoadBalancer {
+	t.Helper()
+
+	lb, err := newTestBuilder(t, upstreams...).WithRetries(retries).Build()
+	require.NoError(t, err)
+	return lb
+}
+
+// newTestBuilder prepares a round-robin load balancer over upstreams whose
+// proxy errors are answered with 502
+func newTestBuilder(t *testing.T, upstreams ...string) *LoadBalancerBuilder {
+	t.Helper()
+
+	builder := NewLoadBalancerBuilder().WithHealthCheckInterval(time.Hour)
+
+	for _, raw := range upstreams {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+
+		proxy := httputil.NewSingleHostReverseProxy(u)
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "Proxy error", http.StatusBadGateway)
+		}
+		builder.WithBackend(u, proxy)
+	}
+	return builder
+}
+
+// closedURL returns the address of a server that no longer accepts connections
+func closedURL() string {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	return s.URL
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("idempotent request retried on another backend", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2}, failing.URL, healthy.URL)
+
+		for i := 0; i < 4; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "healthy", recorder.Body.String())
+			assert.Equal(t, "healthy", recorder.Header().Get("X-Upstream"), "headers of the discarded attempt leaked")
+		}
+		assert.Equal(t, int32(4), healthy.hits.Load())
+	})
+
+	t.Run("status not listed in retryOn is returned", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusInternalServerError, "failing"))
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2, RetryOn: []int{http.StatusBadGateway}}, failing.URL, healthy.URL)
+
+		codes := map[int]int{}
+		for i := 0; i < 4; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			codes[recorder.Code]++
+		}
+		assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusInternalServerError: 2}, codes)
+	})
+
+	t.Run("attempts are capped at maxAttempts", func(t *testing.T) {
+		upstreams := make([]*testUpstream, 3)
+		urls := make([]string, 3)
+		for i := range upstreams {
+			upstreams[i] = newTestUpstream(t, respond(http.StatusBadGateway, "failing"))
+			urls[i] = upstreams[i].URL
+		}
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2}, urls...)
+
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		assert.Equal(t, http.StatusBadGateway, recorder.Code)
+		assert.Equal(t, "failing", recorder.Body.String())
+
+		total := int32(0)
+		for _, u := range upstreams {
+			assert.LessOrEqual(t, u.hits.Load(), int32(1), "a backend was tried twice")
+			total += u.hits.Load()
+		}
+		assert.Equal(t, int32(2), total)
+	})
+
+	t.Run("non-idempotent request is not retried after reaching a backend", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2, RetryUnsent: true}, failing.URL, healthy.URL)
+
+		codes := map[int]int{}
+		for i := 0; i < 4; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("POST", "/", nil))
+			codes[recorder.Code]++
+		}
+		assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusServiceUnavailable: 2}, codes)
+	})
+
+	t.Run("unsent request retried when enabled", func(t *testing.T) {
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+
+		for _, retryUnsent := range []bool{false, true} {
+			lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2, RetryUnsent: retryUnsent}, closedURL(), healthy.URL)
+
+			codes := map[int]int{}
+			for i := 0; i < 4; i++ {
+				recorder := httptest.NewRecorder()
+				lb.ServeHTTP(recorder, httptest.NewRequest("POST", "/", nil))
+				codes[recorder.Code]++
+			}
+
+			if retryUnsent {
+				assert.Equal(t, map[int]int{http.StatusOK: 4}, codes)
+			} else {
+				assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusBadGateway: 2}, codes)
+			}
+		}
+	})
+
+	t.Run("unbuffered request body is not retried", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2}, failing.URL, failing.URL)
+
+		recorder := httptest.NewRecorder()
+		lb.ServeHTTP(recorder, httptest.NewRequest("PUT", "/", strings.NewReader("payload")))
+
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Equal(t, int32(1), failing.hits.Load())
+	})
+
+	t.Run("slow attempt retried after the per try timeout", func(t *testing.T) {
+		slow := newTestUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		})
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{MaxAttempts: 2, PerTryTimeout: 50 * time.Millisecond}, slow.URL, healthy.URL)
+
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			start := time.Now()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		}
+	})
+
+	t.Run("retries stop when the budget is spent", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		lb := newTestLoadBalancer(t, config.RetryConfig{
+			MaxAttempts: 2,
+			Budget:      config.RetryBudgetConfig{Ratio: 0.1, MinRetries: 1},
+		}, failing.URL, healthy.URL)
+
+		// Hold the only retry the budget allows
+		budget := lb.pools[config.DefaultPoolName].retryBudget
+		require.True(t, budget.Acquire())
+
+		codes := map[int]int{}
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			codes[recorder.Code]++
+		}
+		assert.Equal(t, map[int]int{http.StatusOK: 1, http.StatusServiceUnavailable: 1}, codes)
+
+		budget.Release()
+	})
+
+	t.Run("pools spend their own budgets", func(t *testing.T) {
+		failing := newTestUpstream(t, respond(http.StatusServiceUnavailable, "failing"))
+		healthy := newTestUpstream(t, respond(http.StatusOK, "healthy"))
+		failingURL := mustParseURL(t, failing.URL)
+		healthyURL := mustParseURL(t, healthy.URL)
+
+		lb, err := newTestBuilder(t, failing.URL, healthy.URL).
+			WithRetries(config.RetryConfig{
+				MaxAttempts: 2,
+				Budget:      config.RetryBudgetConfig{Ratio: 0.1, MinRetries: 1},
+			}).
+			WithPool("api", serverpool.RoundRobin).
+			WithPoolBackend("api", failingURL, httputil.NewSingleHostReverseProxy(failingURL)).
+			WithPoolBackend("api", healthyURL, httputil.NewSingleHostReverseProxy(healthyURL)).
+			WithRoutes([]config.RouteConfig{{Path: "/api", Pool: "api"}}).
+			Build()
+		require.NoError(t, err)
+
+		// The api pool has used up its budget, which leaves the default
+		// pool's untouched
+		budget := lb.pools["api"].retryBudget
+		require.True(t, budget.Acquire())
+		defer budget.Release()
+
+		for i := 0; i < 2; i++ {
+			recorder := httptest.NewRecorder()
+			lb.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+		}
+	})
+}